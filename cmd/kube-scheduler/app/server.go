@@ -56,9 +56,11 @@ import (
 	"k8s.io/klog/v2"
 	schedulerserverconfig "k8s.io/kubernetes/cmd/kube-scheduler/app/config"
 	"k8s.io/kubernetes/cmd/kube-scheduler/app/options"
+	kubefeatures "k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler"
 	kubeschedulerconfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/latest"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
 	"k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 	"k8s.io/kubernetes/pkg/scheduler/metrics/resources"
 	"k8s.io/kubernetes/pkg/scheduler/profile"
@@ -303,6 +305,14 @@ func newHealthzAndMetricsHandler(config *kubeschedulerconfig.KubeSchedulerConfig
 		}
 		routes.DebugFlags{}.Install(pathRecorderMux, "v", routes.StringFlagPutHandler(logs.GlogSetter))
 	}
+	if utilfeature.DefaultFeatureGate.Enabled(kubefeatures.DynamicGPUPreemption) {
+		// Dynamic GPU scheduling state: idle GPUs per node, elastic jobs,
+		// the last retrieve plan and recent decisions.
+		pathRecorderMux.Handle("/debug/dynamic", preemption.DebugHandler())
+	}
+	// Served regardless of the feature gate so submission tooling can tell a
+	// disabled scheduler from an old one.
+	pathRecorderMux.Handle("/debug/dynamic/capabilities", preemption.CapabilitiesHandler())
 	return pathRecorderMux
 }
 
@@ -242,6 +242,13 @@ const (
 	// that is independent of a Pod.
 	DynamicResourceAllocation featuregate.Feature = "DynamicResourceAllocation"
 
+	// owner: @YoungHoonJun
+	//
+	// Enables the Dynamic GPU preemption path in the scheduler: backfilled pod
+	// retraction and elastic MPIJob scale-out/scale-in. When disabled the
+	// scheduler falls back to the stock preemption behavior.
+	DynamicGPUPreemption featuregate.Feature = "DynamicGPUPreemption"
+
 	// owner: @harche
 	// kep: http://kep.k8s.io/3386
 	// alpha: v1.25
@@ -985,6 +992,8 @@ var defaultKubernetesFeatureGates = map[featuregate.Feature]featuregate.FeatureS
 
 	DynamicResourceAllocation: {Default: false, PreRelease: featuregate.Alpha},
 
+	DynamicGPUPreemption: {Default: false, PreRelease: featuregate.Alpha},
+
 	EventedPLEG: {Default: false, PreRelease: featuregate.Alpha},
 
 	ExecProbeTimeout: {Default: true, PreRelease: featuregate.GA}, // lock to default and remove after v1.22 based on KEP #1972 update
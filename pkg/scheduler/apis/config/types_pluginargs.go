@@ -19,6 +19,7 @@ package config
 import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -41,6 +42,12 @@ type DefaultPreemptionArgs struct {
 	// that play a role in the number of candidates shortlisted. Must be at least
 	// 0 nodes. Defaults to 100 nodes if unspecified.
 	MinCandidateNodesAbsolute int32
+
+	// DynamicPreemption carries the configuration of the Dynamic GPU
+	// preemption path as a raw object; the DefaultPreemption plugin decodes
+	// it into preemption.DynamicPreemptionArgs. Nil keeps the builtin
+	// defaults.
+	DynamicPreemption *runtime.RawExtension
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -250,6 +250,7 @@ func autoConvert_v1_DefaultPreemptionArgs_To_config_DefaultPreemptionArgs(in *v1
 	if err := metav1.Convert_Pointer_int32_To_int32(&in.MinCandidateNodesAbsolute, &out.MinCandidateNodesAbsolute, s); err != nil {
 		return err
 	}
+	out.DynamicPreemption = in.DynamicPreemption
 	return nil
 }
 
@@ -265,6 +266,7 @@ func autoConvert_config_DefaultPreemptionArgs_To_v1_DefaultPreemptionArgs(in *co
 	if err := metav1.Convert_int32_To_Pointer_int32(&in.MinCandidateNodesAbsolute, &out.MinCandidateNodesAbsolute, s); err != nil {
 		return err
 	}
+	out.DynamicPreemption = in.DynamicPreemption
 	return nil
 }
 
@@ -30,6 +30,11 @@ import (
 func (in *DefaultPreemptionArgs) DeepCopyInto(out *DefaultPreemptionArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
+	if in.DynamicPreemption != nil {
+		in, out := &in.DynamicPreemption, &out.DynamicPreemption
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
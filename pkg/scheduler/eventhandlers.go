@@ -189,6 +189,7 @@ func (sched *Scheduler) deletePodFromSchedulingQueue(obj interface{}) {
 	if fwk.RejectWaitingPod(pod.UID) {
 		sched.SchedulingQueue.MoveAllToActiveOrBackoffQueue(logger, queue.AssignedPodDelete, pod, nil, nil)
 	}
+	sched.forgetGangIfGone(pod)
 }
 
 func (sched *Scheduler) addPodToCache(obj interface{}) {
@@ -252,6 +253,7 @@ func (sched *Scheduler) deletePodFromCache(obj interface{}) {
 	}
 
 	sched.SchedulingQueue.MoveAllToActiveOrBackoffQueue(logger, queue.AssignedPodDelete, pod, nil, nil)
+	sched.forgetGangIfGone(pod)
 }
 
 // assignedPod selects pods that are assigned (scheduled and running).
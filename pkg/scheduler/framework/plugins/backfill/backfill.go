@@ -0,0 +1,309 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = names.Backfill
+
+// preFilterStateKey is the key in CycleState to Backfill pre-computed data.
+const preFilterStateKey = "PreFilter" + Name
+
+// Annotations recording why and when a pod was stamped backfilled, so
+// operators and the retraction path can tell a scheduler-placed backfill
+// from one labeled by an external controller.
+const (
+	backfillReasonAnnotation = "scheduler.alpha/backfill-reason"
+	backfillSinceAnnotation  = "scheduler.alpha/backfill-since"
+)
+
+// Backfill decides when a pod is being backfilled into temporarily idle GPUs
+// and stamps the `scheduling-state=backfilled` annotation itself, so external
+// components no longer have to annotate pods.
+type Backfill struct {
+	handle    framework.Handle
+	podLister corelisters.PodLister
+}
+
+var _ framework.PreFilterPlugin = &Backfill{}
+var _ framework.FilterPlugin = &Backfill{}
+var _ framework.ScorePlugin = &Backfill{}
+var _ framework.PermitPlugin = &Backfill{}
+var _ framework.PostBindPlugin = &Backfill{}
+
+// preFilterState computed at PreFilter and used at Filter/Score.
+type preFilterState struct {
+	// requestGPUs is the number of GPUs the pod asks for.
+	requestGPUs int64
+	// backfilling is true when an older pod is still waiting for GPUs, which
+	// means this pod is only filling temporarily idle capacity.
+	backfilling bool
+}
+
+// Clone the prefilter state.
+func (s *preFilterState) Clone() framework.StateData {
+	return s
+}
+
+// Name returns name of the plugin.
+func (pl *Backfill) Name() string {
+	return Name
+}
+
+func podRequestGPUs(pod *v1.Pod) int64 {
+	// Delegates to the shared helper so backfill counts init and sidecar
+	// containers the same way the Dynamic path does.
+	return preemption.PodAcceleratorRequest(pod)
+}
+
+// olderPodWaiting checks whether any older GPU pod is still unscheduled, in
+// which case <pod> is only backfilled into idle GPUs.
+func (pl *Backfill) olderPodWaiting(pod *v1.Pod) bool {
+	pods, err := pl.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Infof("Pods load error in olderPodWaiting")
+		return false
+	}
+	for _, p := range pods {
+		if p.UID == pod.UID {
+			continue
+		}
+		if p.Spec.NodeName != "" || p.Status.Phase != v1.PodPending {
+			continue
+		}
+		if podRequestGPUs(p) == 0 {
+			continue
+		}
+		if p.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			return true
+		}
+		if schedStateOfPod, check := p.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "unscheduled" {
+			return true
+		}
+	}
+	return false
+}
+
+// backfillBudgetExhausted checks whether admitting <requestGPUs> more
+// backfill GPUs would push backfill occupancy past the configured percentage
+// of the pending queue's GPU demand.
+func (pl *Backfill) backfillBudgetExhausted(requestGPUs int64) bool {
+	pods, err := pl.podLister.List(labels.Everything())
+	if err != nil {
+		klog.Infof("Pods load error in backfillBudgetExhausted")
+		return false
+	}
+	var pendingDemand, backfillOccupied int64
+	for _, p := range pods {
+		if p.Spec.NodeName == "" && p.Status.Phase == v1.PodPending {
+			pendingDemand += podRequestGPUs(p)
+			continue
+		}
+		if schedStateOfPod, check := p.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "backfilled" {
+			backfillOccupied += podRequestGPUs(p)
+		}
+	}
+	budget := pendingDemand * preemption.BackfillBudgetPercent() / 100
+	return backfillOccupied+requestGPUs > budget
+}
+
+// PreFilter invoked at the prefilter extension point.
+func (pl *Backfill) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	// Malformed scheduler annotations would misbehave deep inside the
+	// elastic machinery, so they fail the pod here with a clear message.
+	if violations := util.ValidateSchedulerAnnotations(pod); len(violations) > 0 {
+		return nil, framework.NewStatus(framework.Unschedulable, violations[0].String())
+	}
+	requestGPUs := podRequestGPUs(pod)
+	if requestGPUs == 0 {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	backfilling := pl.olderPodWaiting(pod)
+	if backfilling && pl.backfillBudgetExhausted(requestGPUs) {
+		return nil, framework.NewStatus(framework.Unschedulable, "backfill budget exhausted")
+	}
+	state.Write(preFilterStateKey, &preFilterState{
+		requestGPUs: requestGPUs,
+		backfilling: backfilling,
+	})
+	return nil, nil
+}
+
+// PreFilterExtensions do not exist for this plugin.
+func (pl *Backfill) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+func getPreFilterState(cycleState *framework.CycleState) *preFilterState {
+	c, err := cycleState.Read(preFilterStateKey)
+	if err != nil {
+		return nil
+	}
+	s, ok := c.(*preFilterState)
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+func idleGPUsOnNode(nodeInfo *framework.NodeInfo) int64 {
+	// Delegates to the shared helper so idle capacity covers the same
+	// configured resource names the request side counts.
+	return preemption.NodeInfoIdleAccelerators(nodeInfo)
+}
+
+// Filter invoked at the filter extension point. It rejects nodes whose idle
+// GPU count cannot hold the pod.
+func (pl *Backfill) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	s := getPreFilterState(state)
+	if s == nil {
+		return nil
+	}
+	if idleGPUsOnNode(nodeInfo) < s.requestGPUs {
+		return framework.NewStatus(framework.Unschedulable, "not enough idle GPUs")
+	}
+	return nil
+}
+
+// Score invoked at the score extension point. Backfilled pods are packed into
+// the node with the fewest leftover idle GPUs so large idle blocks stay free.
+func (pl *Backfill) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	s := getPreFilterState(state)
+	if s == nil {
+		return 0, nil
+	}
+	nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	leftover := idleGPUsOnNode(nodeInfo) - s.requestGPUs
+	if leftover < 0 {
+		return 0, nil
+	}
+	if leftover > framework.MaxNodeScore {
+		leftover = framework.MaxNodeScore
+	}
+	if s.backfilling {
+		// The fewer GPUs left idle, the better the backfill placement.
+		return framework.MaxNodeScore - leftover, nil
+	}
+	return leftover, nil
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *Backfill) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// Permit holds backfilled pods in the waiting pods map for a short window.
+// A pod parked there is visible to preemption's prepareCandidate and to the
+// Dynamic retraction path, which can reject it without an API deletion when
+// a higher-priority pod arrives during the bind window. The hold normally
+// ends when a Dynamic cycle settles its retraction decision; the timer below
+// is only the fallback for clusters where no such cycle runs before the
+// configured window closes. Regularly scheduled pods bind immediately.
+func (pl *Backfill) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	s := getPreFilterState(state)
+	if s == nil || !s.backfilling {
+		return nil, 0
+	}
+	permitWait := preemption.BackfillPermitWait()
+	// A Permit timeout rejects the pod, which would bounce every backfill;
+	// the fallback timer allows the pod just before the window closes
+	// instead. With a one-second window the margin would fire before the
+	// framework registers the waiting pod, so the delay stays positive.
+	fallbackDelay := permitWait - time.Second
+	if fallbackDelay <= 0 {
+		fallbackDelay = permitWait / 2
+	}
+	timer := time.AfterFunc(fallbackDelay, func() {
+		preemption.StopBackfillHoldTimer(pod.UID)
+		if waitingPod := pl.handle.GetWaitingPod(pod.UID); waitingPod != nil {
+			waitingPod.Allow(Name)
+		}
+	})
+	// Registered so a cycle decision ending the hold early also stops the
+	// timer instead of leaking it until expiry.
+	preemption.TrackBackfillHoldTimer(pod.UID, timer)
+	return framework.NewStatus(framework.Wait), permitWait
+}
+
+// PostBind stamps the scheduling-state annotation depending on whether the
+// pod was backfilled or regularly scheduled.
+func (pl *Backfill) PostBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	// Binding ends any Permit hold, so the fallback timer can go too.
+	preemption.StopBackfillHoldTimer(pod.UID)
+	// Binding closes the plan-to-bound window if an elastic plan was
+	// executed for this pod while it was pending.
+	preemption.ObservePlanToBound(pod)
+	s := getPreFilterState(state)
+	if s == nil {
+		return
+	}
+	schedState := "scheduled"
+	if s.backfilling {
+		schedState = "backfilled"
+	}
+	// An annotation patch instead of a Get+Update: right after bind the
+	// kubelet races with status writes, and a conflict here would lose the
+	// stamp — leaving a backfilled pod invisible to the retraction path.
+	annotations := map[string]interface{}{util.SchedulingStateAnnotation: schedState}
+	if s.backfilling {
+		// The pod occupies GPUs the scheduler may reclaim for an older
+		// pending pod; record why and since when so retractions are auditable.
+		annotations[backfillReasonAnnotation] = "placed into idle GPUs while an older GPU pod is pending"
+		annotations[backfillSinceAnnotation] = time.Now().Format(time.RFC3339)
+	} else {
+		annotations[backfillReasonAnnotation] = nil
+		annotations[backfillSinceAnnotation] = nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		klog.Infof("Fail to encode scheduling-state patch in Backfill PostBind: %v", err)
+		return
+	}
+	if _, err := pl.handle.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.Infof("Fail to update scheduling-state in Backfill PostBind: %v", err)
+		return
+	}
+	klog.Infof("Backfill: %v -> %v", pod.Name, schedState)
+}
+
+// New initializes a new plugin and returns it.
+func New(_ context.Context, _ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	return &Backfill{
+		handle:    handle,
+		podLister: handle.SharedInformerFactory().Core().V1().Pods().Lister(),
+	}, nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backfill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	internalcache "k8s.io/kubernetes/pkg/scheduler/internal/cache"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+var gpuRes = map[v1.ResourceName]string{"nvidia.com/gpu": "2"}
+
+// newBackfillPlugin builds the plugin against a fake clientset holding
+// <pods> and a snapshot of <pods> placed on <nodes>.
+func newBackfillPlugin(t *testing.T, pods []*v1.Pod, nodes []*v1.Node) (*Backfill, context.Context) {
+	t.Helper()
+	logger, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+
+	var objs []runtime.Object
+	for _, pod := range pods {
+		objs = append(objs, pod)
+	}
+	informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(objs...), 0)
+	fwk, err := tf.NewFramework(
+		ctx,
+		[]tf.RegisterPluginFunc{
+			tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		}, "",
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(internalcache.NewSnapshot(pods, nodes)),
+		frameworkruntime.WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	p, err := New(ctx, nil, fwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*Backfill), ctx
+}
+
+func TestBackfillPreFilter(t *testing.T) {
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := metav1.NewTime(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name            string
+		pod             *v1.Pod
+		existingPods    []*v1.Pod
+		wantCode        framework.Code
+		wantBackfilling bool
+	}{
+		{
+			name:     "malformed scheduler annotation fails the pod",
+			pod:      st.MakePod().Name("p").UID("p").Annotation(util.SchedulingStateAnnotation, "pending").Obj(),
+			wantCode: framework.Unschedulable,
+		},
+		{
+			name:     "pods without GPUs are skipped",
+			pod:      st.MakePod().Name("p").UID("p").Obj(),
+			wantCode: framework.Skip,
+		},
+		{
+			name:         "no older GPU pod waiting means a regular placement",
+			pod:          st.MakePod().Name("p").UID("p").CreationTimestamp(newer).Req(gpuRes).Obj(),
+			existingPods: nil,
+			wantCode:     framework.Success,
+		},
+		{
+			name: "older pending GPU pod makes this a backfill",
+			pod:  st.MakePod().Name("p").UID("p").CreationTimestamp(newer).Req(gpuRes).Obj(),
+			existingPods: []*v1.Pod{
+				st.MakePod().Name("older").UID("older").CreationTimestamp(older).Phase(v1.PodPending).
+					Req(map[v1.ResourceName]string{"nvidia.com/gpu": "4"}).Obj(),
+			},
+			wantCode:        framework.Success,
+			wantBackfilling: true,
+		},
+		{
+			name: "backfill past the budget is rejected",
+			pod:  st.MakePod().Name("p").UID("p").CreationTimestamp(newer).Req(gpuRes).Obj(),
+			existingPods: []*v1.Pod{
+				st.MakePod().Name("older").UID("older").CreationTimestamp(older).Phase(v1.PodPending).
+					Req(map[v1.ResourceName]string{"nvidia.com/gpu": "1"}).Obj(),
+			},
+			wantCode: framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, ctx := newBackfillPlugin(t, tt.existingPods, nil)
+			state := framework.NewCycleState()
+			_, status := pl.PreFilter(ctx, state, tt.pod)
+			if status.Code() != tt.wantCode {
+				t.Fatalf("PreFilter() status = %v, want %v", status, tt.wantCode)
+			}
+			if tt.wantCode != framework.Success {
+				return
+			}
+			s := getPreFilterState(state)
+			if s == nil {
+				t.Fatalf("PreFilter() wrote no state")
+			}
+			if s.backfilling != tt.wantBackfilling {
+				t.Errorf("PreFilter() backfilling = %v, want %v", s.backfilling, tt.wantBackfilling)
+			}
+		})
+	}
+}
+
+func TestBackfillFilter(t *testing.T) {
+	node := st.MakeNode().Name("node-a").
+		Capacity(map[v1.ResourceName]string{v1.ResourceCPU: "8", v1.ResourceMemory: "32Gi", "nvidia.com/gpu": "8"}).Obj()
+	busy := st.MakePod().Name("busy").UID("busy").Node("node-a").
+		Req(map[v1.ResourceName]string{"nvidia.com/gpu": "6"}).Obj()
+	nodeInfo := framework.NewNodeInfo(busy)
+	nodeInfo.SetNode(node)
+
+	tests := []struct {
+		name        string
+		requestGPUs int64
+		wantCode    framework.Code
+	}{
+		{
+			name:        "fits into the idle GPUs",
+			requestGPUs: 2,
+			wantCode:    framework.Success,
+		},
+		{
+			name:        "does not fit into the idle GPUs",
+			requestGPUs: 3,
+			wantCode:    framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := &Backfill{}
+			state := framework.NewCycleState()
+			state.Write(preFilterStateKey, &preFilterState{requestGPUs: tt.requestGPUs})
+			status := pl.Filter(context.Background(), state, st.MakePod().Name("p").Obj(), nodeInfo)
+			if status.Code() != tt.wantCode {
+				t.Errorf("Filter() status = %v, want %v", status, tt.wantCode)
+			}
+		})
+	}
+
+	// Without prefilter state the plugin stays out of the way.
+	if status := (&Backfill{}).Filter(context.Background(), framework.NewCycleState(), st.MakePod().Name("p").Obj(), nodeInfo); status != nil {
+		t.Errorf("Filter() without state = %v, want nil", status)
+	}
+}
+
+func TestBackfillScore(t *testing.T) {
+	capacity := map[v1.ResourceName]string{v1.ResourceCPU: "8", v1.ResourceMemory: "32Gi", "nvidia.com/gpu": "8"}
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node-a").Capacity(capacity).Obj(),
+		st.MakeNode().Name("node-b").Capacity(capacity).Obj(),
+	}
+	pods := []*v1.Pod{
+		st.MakePod().Name("busy-a").UID("busy-a").Node("node-a").
+			Req(map[v1.ResourceName]string{"nvidia.com/gpu": "4"}).Obj(),
+		st.MakePod().Name("busy-b").UID("busy-b").Node("node-b").
+			Req(map[v1.ResourceName]string{"nvidia.com/gpu": "6"}).Obj(),
+	}
+
+	tests := []struct {
+		name        string
+		backfilling bool
+		wantScores  map[string]int64
+	}{
+		{
+			name:        "regular placements spread onto the idlest node",
+			backfilling: false,
+			wantScores:  map[string]int64{"node-a": 2, "node-b": 0},
+		},
+		{
+			name:        "backfills pack into the fullest node that fits",
+			backfilling: true,
+			wantScores:  map[string]int64{"node-a": framework.MaxNodeScore - 2, "node-b": framework.MaxNodeScore},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, ctx := newBackfillPlugin(t, pods, nodes)
+			state := framework.NewCycleState()
+			state.Write(preFilterStateKey, &preFilterState{requestGPUs: 2, backfilling: tt.backfilling})
+			for nodeName, want := range tt.wantScores {
+				got, status := pl.Score(ctx, state, st.MakePod().Name("p").Req(gpuRes).Obj(), nodeName)
+				if !status.IsSuccess() {
+					t.Fatalf("Score(%v) status = %v", nodeName, status)
+				}
+				if got != want {
+					t.Errorf("Score(%v) = %v, want %v", nodeName, got, want)
+				}
+			}
+		})
+	}
+}
@@ -25,14 +25,17 @@ import (
 	v1 "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/informers"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	policylisters "k8s.io/client-go/listers/policy/v1"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	"k8s.io/klog/v2"
 	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/validation"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
@@ -41,11 +44,26 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
 	"k8s.io/kubernetes/pkg/scheduler/metrics"
 	"k8s.io/kubernetes/pkg/scheduler/util"
+	"sigs.k8s.io/yaml"
 )
 
 // Name of the plugin used in the plugin registry and configurations.
 const Name = names.DefaultPreemption
 
+// loadDynamicPreemptionArgs overlays the dynamicPreemption block of the
+// plugin's pluginConfig entry, if present, onto the builtin Dynamic
+// preemption defaults.
+func loadDynamicPreemptionArgs(args *config.DefaultPreemptionArgs) (*preemption.DynamicPreemptionArgs, error) {
+	dynArgs := preemption.NewDynamicPreemptionArgs()
+	if args.DynamicPreemption == nil || len(args.DynamicPreemption.Raw) == 0 {
+		return dynArgs, nil
+	}
+	if err := yaml.Unmarshal(args.DynamicPreemption.Raw, dynArgs); err != nil {
+		return nil, fmt.Errorf("parsing dynamicPreemption args: %v", err)
+	}
+	return dynArgs, nil
+}
+
 // DefaultPreemption is a PostFilter plugin implements the preemption logic.
 type DefaultPreemption struct {
 	fh        framework.Handle
@@ -63,7 +81,7 @@ func (pl *DefaultPreemption) Name() string {
 }
 
 // New initializes a new plugin and returns it.
-func New(_ context.Context, dpArgs runtime.Object, fh framework.Handle, fts feature.Features) (framework.Plugin, error) {
+func New(ctx context.Context, dpArgs runtime.Object, fh framework.Handle, fts feature.Features) (framework.Plugin, error) {
 	args, ok := dpArgs.(*config.DefaultPreemptionArgs)
 	if !ok {
 		return nil, fmt.Errorf("got args of type %T, want *DefaultPreemptionArgs", dpArgs)
@@ -71,6 +89,23 @@ func New(_ context.Context, dpArgs runtime.Object, fh framework.Handle, fts feat
 	if err := validation.ValidateDefaultPreemptionArgs(nil, args); err != nil {
 		return nil, err
 	}
+	if utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		dynArgs, err := loadDynamicPreemptionArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		if err := preemption.SetDynamicPreemptionArgs(dynArgs); err != nil {
+			return nil, err
+		}
+		// The elastic-job dynamic client shares the scheduler's own rest
+		// config instead of re-reading the kubeconfig per call.
+		if config := fh.KubeConfig(); config != nil {
+			preemption.SetElasticJobClientConfig(config)
+		}
+		if err := preemption.ValidateElasticJobMappings(fh.ClientSet().Discovery(), dynArgs.ElasticJobMappings); err != nil {
+			return nil, err
+		}
+	}
 	pl := DefaultPreemption{
 		fh:        fh,
 		fts:       fts,
@@ -78,6 +113,37 @@ func New(_ context.Context, dpArgs runtime.Object, fh framework.Handle, fts feat
 		podLister: fh.SharedInformerFactory().Core().V1().Pods().Lister(),
 		pdbLister: getPDBLister(fh.SharedInformerFactory()),
 	}
+	if utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		// The planning paths read pods per node through this shared index
+		// instead of per-node apiserver lists.
+		if err := preemption.InitPodNodeIndex(fh.SharedInformerFactory().Core().V1().Pods().Informer()); err != nil {
+			return nil, err
+		}
+		// Per-node GPU ledgers maintained from informer events; planning and
+		// scoring read them instead of walking pods.
+		if err := preemption.InitGPUAllocationCache(fh.SharedInformerFactory().Core().V1().Pods().Informer(), fh.SharedInformerFactory().Core().V1().Nodes().Informer()); err != nil {
+			return nil, err
+		}
+		// Scale jobs whose GPU-hour budget ran out back to baseline.
+		sweeper := preemption.Evaluator{
+			PluginName: names.DefaultPreemption,
+			Handler:    fh,
+			PodLister:  pl.podLister,
+			PdbLister:  pl.pdbLister,
+		}
+		go sweeper.RunBudgetSweep(ctx)
+		// Promote long-running backfilled pods while the queue is quiet.
+		go sweeper.RunBackfillPromotion(ctx)
+		// Take back scheduler-added replicas once their preemptor is served.
+		go sweeper.RunRetractManager(ctx)
+		// Expire SchedulingDecision audit objects past their TTL.
+		go sweeper.RunDecisionGC(ctx)
+		// Apply queued scale intents when the async executor is enabled.
+		go sweeper.RunScaleExecutor(ctx)
+		// Track MPIJob CRD presence so elastic features degrade and recover
+		// without a restart.
+		go preemption.RunElasticCRDProbe(ctx, fh.ClientSet().Discovery())
+	}
 	return &pl, nil
 }
 
@@ -96,11 +162,19 @@ func (pl *DefaultPreemption) PostFilter(ctx context.Context, state *framework.Cy
 		Interface:  pl,
 	}
 
-	// result, status := pe.Preempt(ctx, pod, m)
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		result, status := pe.Preempt(ctx, pod, m)
+		msg := status.Message()
+		if len(msg) > 0 {
+			return result, framework.NewStatus(status.Code(), "preemption: "+msg)
+		}
+		return result, status
+	}
+
 	result, status := pe.Dynamic(ctx, pod, m)
+	preemption.RecordDynamicDecision(pod, status.Code().String(), status.Message())
 	msg := status.Message()
 	if len(msg) > 0 {
-		// return result, framework.NewStatus(status.Code(), "Preemption: "+msg)
 		return result, framework.NewStatus(status.Code(), "Dynamic Resource Allocation: "+msg)
 	}
 	return result, status
@@ -345,3 +419,68 @@ func filterPodsWithPDBViolation(podInfos []*framework.PodInfo, pdbs []*policy.Po
 func getPDBLister(informerFactory informers.SharedInformerFactory) policylisters.PodDisruptionBudgetLister {
 	return informerFactory.Policy().V1().PodDisruptionBudgets().Lister()
 }
+
+// EventsToRegister re-activates pods the Dynamic path reported unschedulable
+// exactly when the capacity it arranged shows up: a backfilled pod being
+// deleted (retraction) or an MPIJob losing workers (scale-in). Without these
+// hints a rejected pod waits for the periodic queue flush.
+func (pl *DefaultPreemption) EventsToRegister() []framework.ClusterEventWithHint {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return nil
+	}
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}, QueueingHintFn: pl.isSchedulableAfterPodDeleted},
+		{Event: framework.ClusterEvent{Resource: framework.GVK("mpijobs.v1.kubeflow.org"), ActionType: framework.Update}, QueueingHintFn: pl.isSchedulableAfterMPIJobChange},
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add}, QueueingHintFn: pl.isSchedulableAfterNodeAdded},
+	}
+}
+
+// isSchedulableAfterNodeAdded queues the pending pod when a GPU node joins
+// the cluster, which is how a ProvisioningRequest handed to the cluster
+// autoscaler eventually resolves a GPU shortage. CPU-only nodes cannot help
+// a pod the Dynamic path rejected and are skipped.
+func (pl *DefaultPreemption) isSchedulableAfterNodeAdded(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	addedNode, _, err := util.As[*v1.Node](nil, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if preemption.NodeAcceleratorCapacity(addedNode) > 0 {
+		logger.V(5).Info("GPU node added, queueing", "pod", klog.KObj(pod), "node", klog.KObj(addedNode))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
+
+// isSchedulableAfterPodDeleted queues the pending pod when a backfilled pod
+// was deleted, since that is how retraction frees GPUs.
+func (pl *DefaultPreemption) isSchedulableAfterPodDeleted(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	deletedPod, _, err := util.As[*v1.Pod](oldObj, nil)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if deletedPod.Annotations[util.SchedulingStateAnnotation] == "backfilled" {
+		logger.V(5).Info("backfilled pod deleted, queueing", "pod", klog.KObj(pod), "victim", klog.KObj(deletedPod))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
+
+// isSchedulableAfterMPIJobChange queues the pending pod when an MPIJob's
+// worker count decreased, since that is how scale-in frees GPUs.
+func (pl *DefaultPreemption) isSchedulableAfterMPIJobChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldJob, ok := oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return framework.Queue, fmt.Errorf("unexpected old object %T", oldObj)
+	}
+	newJob, ok := newObj.(*unstructured.Unstructured)
+	if !ok {
+		return framework.Queue, fmt.Errorf("unexpected new object %T", newObj)
+	}
+	oldReplicas, oldFound, _ := unstructured.NestedInt64(oldJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+	newReplicas, newFound, _ := unstructured.NestedInt64(newJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+	if oldFound && newFound && newReplicas < oldReplicas {
+		logger.V(5).Info("MPIJob scaled in, queueing", "pod", klog.KObj(pod), "mpijob", klog.KObj(newJob))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaultpreemption
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	internalcache "k8s.io/kubernetes/pkg/scheduler/internal/cache"
+	internalqueue "k8s.io/kubernetes/pkg/scheduler/internal/queue"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+)
+
+// BenchmarkDynamic measures the planning cost of the Dynamic PostFilter path
+// on large fake clusters: wall latency, allocations, and the number of
+// apiserver calls issued per decision. The cluster is built so the plan is
+// infeasible, keeping the benchmark side-effect free.
+func BenchmarkDynamic(b *testing.B) {
+	cases := []struct {
+		nodes       int
+		podsPerNode int
+	}{
+		{nodes: 100, podsPerNode: 30},
+		{nodes: 1000, podsPerNode: 30},
+		{nodes: 5000, podsPerNode: 30},
+	}
+	for _, tc := range cases {
+		name := fmt.Sprintf("%dNodes/%dPods", tc.nodes, tc.nodes*tc.podsPerNode)
+		b.Run(name, func(b *testing.B) {
+			if testing.Short() && tc.nodes > 1000 {
+				b.Skipf("skipping %s in short mode", name)
+			}
+			benchmarkDynamic(b, tc.nodes, tc.podsPerNode)
+		})
+	}
+}
+
+func benchmarkDynamic(b *testing.B, nodeCount, podsPerNode int) {
+	logger, ctx := ktesting.NewTestContext(b)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var objs []runtime.Object
+	nodes := make([]*v1.Node, 0, nodeCount)
+	pods := make([]*v1.Pod, 0, nodeCount*podsPerNode)
+	for i := 0; i < nodeCount; i++ {
+		node := st.MakeNode().Name(fmt.Sprintf("node-%d", i)).
+			Capacity(map[v1.ResourceName]string{"nvidia.com/gpu": "8", v1.ResourceCPU: "64", v1.ResourceMemory: "256Gi"}).Obj()
+		nodes = append(nodes, node)
+		objs = append(objs, node)
+		for j := 0; j < podsPerNode; j++ {
+			pod := st.MakePod().Name(fmt.Sprintf("pod-%d-%d", i, j)).UID(fmt.Sprintf("pod-%d-%d", i, j)).
+				Namespace(v1.NamespaceDefault).Node(node.Name).
+				Req(map[v1.ResourceName]string{"nvidia.com/gpu": "1"}).
+				Annotation("scheduling-state", "backfilled").
+				Annotation("model-name", "resnet50").Obj()
+			pods = append(pods, pod)
+			objs = append(objs, pod)
+		}
+	}
+	// The preemptor is newer than every backfilled pod and asks for more GPUs
+	// than any plan can free, so Dynamic scans the whole cluster but executes
+	// nothing.
+	preemptor := st.MakePod().Name("preemptor").UID("preemptor").Namespace(v1.NamespaceDefault).
+		Req(map[v1.ResourceName]string{"nvidia.com/gpu": "64"}).
+		CreationTimestamp(metav1.Time{Time: time.Now().Add(time.Hour)}).Obj()
+	objs = append(objs, preemptor)
+
+	client := clientsetfake.NewSimpleClientset(objs...)
+	var apiCalls int64
+	client.PrependReactor("*", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt64(&apiCalls, 1)
+		return false, nil, nil
+	})
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	fwk, err := tf.NewFramework(
+		ctx,
+		[]tf.RegisterPluginFunc{
+			tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		},
+		"",
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithEventRecorder(&events.FakeRecorder{}),
+		frameworkruntime.WithPodNominator(internalqueue.NewPodNominator(informerFactory.Core().V1().Pods().Lister())),
+		frameworkruntime.WithSnapshotSharedLister(internalcache.NewSnapshot(pods, nodes)),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithLogger(logger),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	pl := DefaultPreemption{
+		fh:        fwk,
+		podLister: informerFactory.Core().V1().Pods().Lister(),
+		pdbLister: getPDBLister(informerFactory),
+		args:      *getDefaultDefaultPreemptionArgs(),
+	}
+	pe := preemption.Evaluator{
+		PluginName: names.DefaultPreemption,
+		Handler:    fwk,
+		PodLister:  pl.podLister,
+		PdbLister:  pl.pdbLister,
+		State:      framework.NewCycleState(),
+		Interface:  &pl,
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		atomic.StoreInt64(&apiCalls, 0)
+		// A fresh preemptor name per iteration keeps the per-pod elastic
+		// backoff from short-circuiting the measured path.
+		p := preemptor.DeepCopy()
+		p.Name = fmt.Sprintf("preemptor-%d", i)
+		pe.Dynamic(ctx, p, make(framework.NodeToStatusMap))
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&apiCalls)), "apicalls/op")
+}
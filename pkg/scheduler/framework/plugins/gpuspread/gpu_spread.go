@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpuspread
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+)
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = names.GPUSpread
+
+// GPUSpread scores nodes by how many accelerators stay idle after placing
+// the pod — the inverse of the Backfill packing score. Profiles serving
+// inference enable it instead, trading fragmentation for blast radius: one
+// node failure then takes out as few GPU pods as possible. It shares the
+// accelerator accounting with the Dynamic preemption path, so both agree on
+// what counts as a GPU.
+type GPUSpread struct {
+	handle framework.Handle
+}
+
+var _ framework.ScorePlugin = &GPUSpread{}
+
+// Name returns name of the plugin.
+func (pl *GPUSpread) Name() string {
+	return Name
+}
+
+// Score invoked at the score extension point. Nodes keeping the most idle
+// accelerators after the pod lands score highest.
+func (pl *GPUSpread) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	requestGPUs := preemption.PodAcceleratorRequest(pod)
+	if requestGPUs == 0 {
+		return 0, nil
+	}
+	idle, ready := preemption.NodeIdleGPUs(nodeName)
+	if !ready {
+		nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+		if err != nil {
+			return 0, framework.AsStatus(err)
+		}
+		idle = preemption.NodeInfoIdleAccelerators(nodeInfo)
+	}
+	leftover := idle - requestGPUs
+	if leftover < 0 {
+		return 0, nil
+	}
+	if leftover > framework.MaxNodeScore {
+		leftover = framework.MaxNodeScore
+	}
+	return leftover, nil
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *GPUSpread) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// New initializes a new plugin and returns it.
+func New(_ context.Context, _ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	return &GPUSpread{handle: handle}, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gpuspread
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	internalcache "k8s.io/kubernetes/pkg/scheduler/internal/cache"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+)
+
+func TestGPUSpreadScore(t *testing.T) {
+	capacity := map[v1.ResourceName]string{v1.ResourceCPU: "8", v1.ResourceMemory: "32Gi", "nvidia.com/gpu": "8"}
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node-idle").Capacity(capacity).Obj(),
+		st.MakeNode().Name("node-busy").Capacity(capacity).Obj(),
+	}
+	pods := []*v1.Pod{
+		st.MakePod().Name("busy").UID("busy").Node("node-busy").
+			Req(map[v1.ResourceName]string{"nvidia.com/gpu": "6"}).Obj(),
+	}
+
+	logger, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0)
+	fwk, err := tf.NewFramework(
+		ctx,
+		[]tf.RegisterPluginFunc{
+			tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		}, "",
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(internalcache.NewSnapshot(pods, nodes)),
+		frameworkruntime.WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(ctx, nil, fwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := p.(*GPUSpread)
+
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		nodeName string
+		want     int64
+	}{
+		{
+			name:     "pods without GPUs score zero",
+			pod:      st.MakePod().Name("p").Obj(),
+			nodeName: "node-idle",
+			want:     0,
+		},
+		{
+			name:     "the idlest node keeps the most GPUs free",
+			pod:      st.MakePod().Name("p").Req(map[v1.ResourceName]string{"nvidia.com/gpu": "2"}).Obj(),
+			nodeName: "node-idle",
+			want:     6,
+		},
+		{
+			name:     "a busy node scores its leftover",
+			pod:      st.MakePod().Name("p").Req(map[v1.ResourceName]string{"nvidia.com/gpu": "2"}).Obj(),
+			nodeName: "node-busy",
+			want:     0,
+		},
+		{
+			name:     "a pod that does not fit scores zero",
+			pod:      st.MakePod().Name("p").Req(map[v1.ResourceName]string{"nvidia.com/gpu": "4"}).Obj(),
+			nodeName: "node-busy",
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, status := pl.Score(ctx, framework.NewCycleState(), tt.pod, tt.nodeName)
+			if !status.IsSuccess() {
+				t.Fatalf("Score() status = %v", status)
+			}
+			if got != tt.want {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package names
 
 const (
+	Backfill                        = "Backfill"
+	GPUSpread                       = "GPUSpread"
+	PlacementTracker                = "PlacementTracker"
 	PrioritySort                    = "PrioritySort"
 	DefaultBinder                   = "DefaultBinder"
 	DefaultPreemption               = "DefaultPreemption"
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementtracker
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+)
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = names.PlacementTracker
+
+// PlacementTracker records every GPU pod's placement into the in-memory
+// store the Dynamic preemption path plans from, so questions like "which
+// nodes host this job" stop requiring another walk over all pods.
+type PlacementTracker struct{}
+
+var _ framework.PostBindPlugin = &PlacementTracker{}
+
+// Name returns name of the plugin.
+func (pl *PlacementTracker) Name() string {
+	return Name
+}
+
+// PostBind records where the pod landed. Binding already succeeded, so the
+// store write cannot fail the pod.
+func (pl *PlacementTracker) PostBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	preemption.RecordPlacement(pod, nodeName)
+}
+
+// New initializes a new plugin and returns it.
+func New(_ context.Context, _ runtime.Object, _ framework.Handle) (framework.Plugin, error) {
+	return &PlacementTracker{}, nil
+}
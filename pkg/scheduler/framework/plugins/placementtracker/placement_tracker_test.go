@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementtracker
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+)
+
+// TestPlacementTrackerPostBind checks the plugin wires PostBind into the
+// placement store without needing a handle; the store's own semantics are
+// covered in the preemption package.
+func TestPlacementTrackerPostBind(t *testing.T) {
+	p, err := New(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, ok := p.(framework.PostBindPlugin)
+	if !ok {
+		t.Fatalf("New() did not return a PostBind plugin")
+	}
+	if pl.Name() != Name {
+		t.Errorf("Name() = %v, want %v", pl.Name(), Name)
+	}
+
+	pods := []*v1.Pod{
+		st.MakePod().Namespace("my-ns").Name("tracked-gpu-pod").
+			Req(map[v1.ResourceName]string{"nvidia.com/gpu": "1"}).Obj(),
+		st.MakePod().Namespace("my-ns").Name("untracked-cpu-pod").Obj(),
+	}
+	defer func() {
+		for _, pod := range pods {
+			preemption.ForgetPlacement(pod.Namespace, pod.Name)
+		}
+	}()
+	for _, pod := range pods {
+		pl.PostBind(context.Background(), framework.NewCycleState(), pod, "node-a")
+	}
+}
@@ -18,17 +18,19 @@ package queuesort
 
 import (
 	"context"
-	"fmt"
 	"strings"
+	"sync"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	clientset "k8s.io/client-go/kubernetes"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/tools/cache"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
-	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
+	"k8s.io/kubernetes/pkg/scheduler/util"
 )
 
 // Name is the name of the plugin used in the plugin registry and configurations.
@@ -36,7 +38,19 @@ const Name = names.PrioritySort
 
 // PrioritySort is a plugin that implements Priority based sorting.
 type PrioritySort struct {
-	client clientset.Interface
+	lock sync.RWMutex
+	// runningMPIJobPods counts the running pods of each MPIJob, maintained by
+	// pod informer events so Less never talks to the API server.
+	runningMPIJobPods map[string]int
+	// gangFirstSeen pins each MPIJob to the earliest queue timestamp of any
+	// of its members, so the whole gang dequeues consecutively instead of
+	// interleaving with other jobs into deadlocked partial gangs. Entries
+	// retire through gangQueuedPods once the job has no member waiting for a
+	// node, so the map does not grow with every job ever queued.
+	gangFirstSeen map[string]time.Time
+	// gangQueuedPods counts each MPIJob's unbound pods, maintained by the
+	// same informer events as runningMPIJobPods.
+	gangQueuedPods map[string]int
 }
 
 var _ framework.QueueSortPlugin = &PrioritySort{}
@@ -49,6 +63,10 @@ func (pl *PrioritySort) Name() string {
 func (pl *PrioritySort) checkMPIJob(podName string) (string, bool) {
 	podNameSlice := strings.Split(podName, "-")
 
+	if len(podNameSlice) < 2 {
+		return "", false
+	}
+
 	if podNameSlice[len(podNameSlice)-1] == "launcher" {
 		MPIJobName := strings.Join(podNameSlice[:len(podNameSlice)-1], "-")
 		return MPIJobName, true
@@ -59,29 +77,72 @@ func (pl *PrioritySort) checkMPIJob(podName string) (string, bool) {
 	return "", false
 }
 
+// isLauncherPod reports whether the pod is an MPIJob launcher.
+func isLauncherPod(podName string) bool {
+	return strings.HasSuffix(podName, "-launcher")
+}
+
+// gangTimestamp returns the ordering timestamp shared by every member of the
+// MPIJob: the earliest member timestamp seen so far.
+func (pl *PrioritySort) gangTimestamp(MPIJobName string, podTimestamp time.Time) time.Time {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	if seen, check := pl.gangFirstSeen[MPIJobName]; check && seen.Before(podTimestamp) {
+		return seen
+	}
+	pl.gangFirstSeen[MPIJobName] = podTimestamp
+	return podTimestamp
+}
+
+// isMPIJobInNode reports whether the MPIJob already has a running pod, from
+// the informer-fed index.
 func (pl *PrioritySort) isMPIJobInNode(MPIJobName string) bool {
-	nodes, err := pl.client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		klog.Infof("Node info error")
-		return false
-	}
-	for _, node := range nodes.Items {
-		pods, err := pl.client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name)})
-		if err != nil {
-			klog.Infof("PodList load error")
-			continue
-		}
-		for _, pod := range pods.Items {
-			if pod.Namespace != "my-ns" {
-				continue
-			}
-			runningPodIdx, isRunningPodMPIJob := pl.checkMPIJob(pod.Name)
-			if isRunningPodMPIJob && runningPodIdx == MPIJobName {
-				return true
-			}
-		}
+	pl.lock.RLock()
+	defer pl.lock.RUnlock()
+	return pl.runningMPIJobPods[MPIJobName] > 0
+}
+
+// trackGangPod adjusts the unbound-pod count of the pod's MPIJob by
+// <delta>; once no member waits for a node the gang's pinned timestamp is
+// dropped.
+func (pl *PrioritySort) trackGangPod(pod *v1.Pod, delta int) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return
+	}
+	if pod.Namespace != util.ElasticJobNamespace() || pod.Spec.NodeName != "" {
+		return
+	}
+	MPIJobName, isMPIJob := pl.checkMPIJob(pod.Name)
+	if !isMPIJob {
+		return
+	}
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	pl.gangQueuedPods[MPIJobName] += delta
+	if pl.gangQueuedPods[MPIJobName] <= 0 {
+		delete(pl.gangQueuedPods, MPIJobName)
+		delete(pl.gangFirstSeen, MPIJobName)
+	}
+}
+
+// trackPod adjusts the running-pod count of the pod's MPIJob by <delta>.
+func (pl *PrioritySort) trackPod(pod *v1.Pod, delta int) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return
+	}
+	if pod.Namespace != util.ElasticJobNamespace() || pod.Spec.NodeName == "" {
+		return
+	}
+	MPIJobName, isMPIJob := pl.checkMPIJob(pod.Name)
+	if !isMPIJob {
+		return
+	}
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	pl.runningMPIJobPods[MPIJobName] += delta
+	if pl.runningMPIJobPods[MPIJobName] <= 0 {
+		delete(pl.runningMPIJobPods, MPIJobName)
 	}
-	return false
 }
 
 // Less is the function used by the activeQ heap algorithm to sort pods.
@@ -90,45 +151,121 @@ func (pl *PrioritySort) isMPIJobInNode(MPIJobName string) bool {
 func (pl *PrioritySort) Less(pInfo1, pInfo2 *framework.QueuedPodInfo) bool {
 	p1 := corev1helpers.PodPriority(pInfo1.Pod)
 	p2 := corev1helpers.PodPriority(pInfo2.Pod)
-	p1MPIJobName, isP1MPIJob := pl.checkMPIJob(pInfo1.Pod.Name)
-	p2MPIJobName, isP2MPIJob := pl.checkMPIJob(pInfo2.Pod.Name)
-
-	klog.Infof("p1MPIJobName : %v, isP1MPIJob : %v", p1MPIJobName, isP1MPIJob)
-	klog.Infof("p2MPIJobName : %v, isP2MPIJob : %v", p2MPIJobName, isP2MPIJob)
-
-	if isP1MPIJob != isP2MPIJob {
-		klog.Infof("QUEUEING IS OK")
-		if isP1MPIJob && pl.isMPIJobInNode(p1MPIJobName) {
-			return true
-		} else if isP2MPIJob && pl.isMPIJobInNode(p2MPIJobName) {
-			return false
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return (p1 > p2) || (p1 == p2 && pInfo1.Timestamp.Before(pInfo2.Timestamp))
+	}
+	// Fair queueing: GPU pods of different tenants order by how far each
+	// tenant sits under its configured share, not by raw priority.
+	if r1, ok1 := util.FairShareRank(pInfo1.Pod); ok1 {
+		if r2, ok2 := util.FairShareRank(pInfo2.Pod); ok2 && r1 != r2 {
+			return r1 < r2
 		}
 	}
+	boost := util.MPIJobBoost()
+	// Gang ordering only applies to pods in the elastic namespace; matching
+	// names elsewhere (a db-worker-0, say) must not pin gangFirstSeen entries
+	// that trackGangPod, which filters on the same namespace, never retires.
+	p1MPIJobName, isP1MPIJob := "", false
+	if pInfo1.Pod.Namespace == util.ElasticJobNamespace() {
+		p1MPIJobName, isP1MPIJob = pl.checkMPIJob(pInfo1.Pod.Name)
+	}
+	p2MPIJobName, isP2MPIJob := "", false
+	if pInfo2.Pod.Namespace == util.ElasticJobNamespace() {
+		p2MPIJobName, isP2MPIJob = pl.checkMPIJob(pInfo2.Pod.Name)
+	}
+	boostP1 := isP1MPIJob && boost.AppliesTo(pInfo1.Pod)
+	boostP2 := isP2MPIJob && boost.AppliesTo(pInfo2.Pod)
 
-	if _, check_1 := pInfo1.Pod.ObjectMeta.Annotations["retract-check-var"]; check_1 {
-		if _, check_2 := pInfo2.Pod.ObjectMeta.Annotations["retract-check-var"]; check_2 {
-			// Both Pods have been retracted
-			p1Timestamp, _ := time.Parse(time.RFC3339, pInfo1.Pod.ObjectMeta.Annotations["retract-check-var"])
-			p2Timestamp, _ := time.Parse(time.RFC3339, pInfo2.Pod.ObjectMeta.Annotations["retract-check-var"])
-			return (p1 > p2) || (p1 == p2 && p1Timestamp.Before(p2Timestamp))
+	if boostP1 != boostP2 {
+		if boost.BoostWeight > 0 {
+			// A weighted boost raises the pod's priority but still lets a
+			// sufficiently higher-priority pod go first.
+			if boostP1 && pl.isMPIJobInNode(p1MPIJobName) {
+				p1 += int32(boost.BoostWeight)
+			} else if boostP2 && pl.isMPIJobInNode(p2MPIJobName) {
+				p2 += int32(boost.BoostWeight)
+			}
 		} else {
-			// Only p1 have been retracted
-			p1Timestamp, _ := time.Parse(time.RFC3339, pInfo1.Pod.ObjectMeta.Annotations["retract-check-var"])
-			return (p1 > p2) || (p1 == p2 && p1Timestamp.Before(pInfo2.Timestamp))
+			if boostP1 && pl.isMPIJobInNode(p1MPIJobName) {
+				return true
+			} else if boostP2 && pl.isMPIJobInNode(p2MPIJobName) {
+				return false
+			}
 		}
-	} else {
-		if _, check_3 := pInfo2.Pod.ObjectMeta.Annotations["retract-check-var"]; check_3 {
-			// Only p2 have been retracted
-			p2Timestamp, _ := time.Parse(time.RFC3339, pInfo2.Pod.ObjectMeta.Annotations["retract-check-var"])
-			return (p1 > p2) || (p1 == p2 && pInfo1.Timestamp.Before(p2Timestamp))
-		} else {
-			// Neither Pod has ever been retracted (Default)
-			return (p1 > p2) || (p1 == p2 && pInfo1.Timestamp.Before(pInfo2.Timestamp))
+	}
+
+	// A retracted pod inherits the queue position of the pod it was retracted
+	// for; malformed or future timestamps fall back to the queue timestamp.
+	p1Timestamp, _ := util.RetractionTimestamp(pInfo1.Pod, pInfo1.Timestamp)
+	p2Timestamp, _ := util.RetractionTimestamp(pInfo2.Pod, pInfo2.Timestamp)
+
+	// Members of one gang dequeue consecutively: launcher first, then the
+	// workers in timestamp order.
+	if isP1MPIJob && isP2MPIJob && p1MPIJobName == p2MPIJobName && p1 == p2 {
+		if l1, l2 := isLauncherPod(pInfo1.Pod.Name), isLauncherPod(pInfo2.Pod.Name); l1 != l2 {
+			return l1
 		}
+		return p1Timestamp.Before(p2Timestamp)
+	}
+	// Against everything else, a gang member orders by its gang's earliest
+	// timestamp so two gangs cannot interleave.
+	if isP1MPIJob {
+		p1Timestamp = pl.gangTimestamp(p1MPIJobName, p1Timestamp)
+	}
+	if isP2MPIJob {
+		p2Timestamp = pl.gangTimestamp(p2MPIJobName, p2Timestamp)
 	}
+	if p1 == p2 && isP1MPIJob && isP2MPIJob && p1Timestamp.Equal(p2Timestamp) {
+		return p1MPIJobName < p2MPIJobName
+	}
+	return (p1 > p2) || (p1 == p2 && p1Timestamp.Before(p2Timestamp))
 }
 
 // New initializes a new plugin and returns it.
 func New(_ context.Context, _ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
-	return &PrioritySort{}, nil
+	pl := &PrioritySort{
+		runningMPIJobPods: make(map[string]int),
+		gangFirstSeen:     make(map[string]time.Time),
+		gangQueuedPods:    make(map[string]int),
+	}
+	// Test harnesses build frameworks without an informer factory; the
+	// running-pod index then simply stays empty.
+	factory := handle.SharedInformerFactory()
+	if factory == nil {
+		return pl, nil
+	}
+	factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				pl.trackPod(pod, 1)
+				pl.trackGangPod(pod, 1)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldPod, ok := oldObj.(*v1.Pod); ok {
+				pl.trackPod(oldPod, -1)
+				pl.trackGangPod(oldPod, -1)
+			}
+			if newPod, ok := newObj.(*v1.Pod); ok {
+				pl.trackPod(newPod, 1)
+				pl.trackGangPod(newPod, 1)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			pod, ok := obj.(*v1.Pod)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				pod, ok = tombstone.Obj.(*v1.Pod)
+				if !ok {
+					return
+				}
+			}
+			pl.trackPod(pod, -1)
+			pl.trackGangPod(pod, -1)
+		},
+	})
+	return pl, nil
 }
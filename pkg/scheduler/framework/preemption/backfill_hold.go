@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The fallback timers backing the Backfill plugin's Permit holds, keyed by
+// pod UID. The registry lives here rather than in the plugin so that
+// releaseBackfillHolds, which ends holds early on cycle decisions, can stop
+// a pod's timer instead of leaving it pending until it fires.
+var (
+	backfillHoldLock   sync.Mutex
+	backfillHoldTimers = map[types.UID]*time.Timer{}
+)
+
+// TrackBackfillHoldTimer registers the fallback timer behind a backfill
+// Permit hold. A previously registered timer for the pod is stopped first.
+func TrackBackfillHoldTimer(uid types.UID, timer *time.Timer) {
+	backfillHoldLock.Lock()
+	defer backfillHoldLock.Unlock()
+	if previous, check := backfillHoldTimers[uid]; check {
+		previous.Stop()
+	}
+	backfillHoldTimers[uid] = timer
+}
+
+// StopBackfillHoldTimer stops and drops the pod's fallback timer, called
+// when its hold ends for any reason — cycle decision, fallback expiry, or
+// binding.
+func StopBackfillHoldTimer(uid types.UID) {
+	backfillHoldLock.Lock()
+	defer backfillHoldLock.Unlock()
+	if timer, check := backfillHoldTimers[uid]; check {
+		timer.Stop()
+		delete(backfillHoldTimers, uid)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// backfillLeaseAnnotation holds a per-pod lease duration (Go duration
+// syntax, e.g. "30m"). A backfilled pod that has run past its lease is
+// promoted to a regular pod and stops being a retraction victim, so
+// long-running opportunistic jobs eventually gain stability.
+const backfillLeaseAnnotation = "scheduler.alpha/backfill-lease"
+
+// backfillLeaseExpired reports whether the pod carries a backfill lease
+// that has already run out. Pods without the annotation, with a malformed
+// duration or not yet started keep the default retractable behavior.
+func backfillLeaseExpired(pod *v1.Pod) bool {
+	lease, check := pod.Annotations[backfillLeaseAnnotation]
+	if !check {
+		return false
+	}
+	duration, err := time.ParseDuration(lease)
+	if err != nil {
+		klog.Infof("Unreadable backfill lease %q on pod %v: %v", lease, pod.Name, err)
+		return false
+	}
+	if pod.Status.StartTime == nil {
+		return false
+	}
+	return time.Since(pod.Status.StartTime.Time) >= duration
+}
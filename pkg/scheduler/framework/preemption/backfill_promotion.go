@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// promotionSweepInterval is how often backfilled pods are considered for
+// promotion out of retractable status.
+const promotionSweepInterval = 2 * time.Minute
+
+// RunBackfillPromotion periodically promotes long-running backfilled pods to
+// regular scheduled status while queue pressure is low, until ctx is done.
+// A promoted pod is no longer a retraction victim, so backfill stops being a
+// death sentence for workloads that got their chance while the cluster was
+// quiet.
+func (ev *Evaluator) RunBackfillPromotion(ctx context.Context) {
+	wait.UntilWithContext(ctx, ev.promoteBackfilledPods, promotionSweepInterval)
+}
+
+func (ev *Evaluator) promoteBackfilledPods(ctx context.Context) {
+	pods, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Pod list error in promoteBackfilledPods")
+		return
+	}
+
+	// Queue pressure and the longest observed wait come from the pending GPU
+	// pods themselves: a pod that has waited ten minutes is expected to wait
+	// about that long again.
+	var pendingDemand int64
+	var maxPendingWait time.Duration
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" || pod.Status.Phase != v1.PodPending {
+			continue
+		}
+		requestGPUs := podAcceleratorRequest(&pod)
+		if requestGPUs == 0 {
+			continue
+		}
+		pendingDemand += requestGPUs
+		if waited := time.Since(pod.CreationTimestamp.Time); waited > maxPendingWait {
+			maxPendingWait = waited
+		}
+	}
+
+	nodes, err := ev.Handler.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Node info error in promoteBackfilledPods")
+		return
+	}
+	// Queue pressure is low when the idle GPUs already cover the pending
+	// demand; promoting then cannot starve anyone. Expired-lease pods are
+	// promoted either way, since the lease is a promise to the workload.
+	lowPressure := pendingDemand <= ev.idleGPUsinNodes(ctx, nodes)
+
+	for _, pod := range pods.Items {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; !check || schedStateOfPod != util.SchedulingStateBackfilled {
+			continue
+		}
+		if pod.Status.Phase != v1.PodRunning || pod.Status.StartTime == nil {
+			continue
+		}
+		// A backfilled pod that has already outrun the estimated wait of
+		// every pending pod has earned its place.
+		if !backfillLeaseExpired(&pod) {
+			if !lowPressure || time.Since(pod.Status.StartTime.Time) <= maxPendingWait {
+				continue
+			}
+		}
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%s"}}}`, util.SchedulingStateAnnotation, util.SchedulingStateScheduled))
+		if _, err := ev.Handler.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			klog.Infof("Failed to promote backfilled pod %v: %v", pod.Name, err)
+			continue
+		}
+		klog.Infof("Promoted backfilled pod %v after %v of runtime", pod.Name, time.Since(pod.Status.StartTime.Time).Round(time.Second))
+	}
+}
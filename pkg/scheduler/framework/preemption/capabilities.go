@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"encoding/json"
+	"net/http"
+
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/kubernetes/pkg/features"
+)
+
+// Capabilities describes which elastic features this scheduler has active,
+// so external tooling can adapt its submission behavior to the scheduler
+// instead of guessing from the chart or version.
+type Capabilities struct {
+	// DynamicGPUPreemption reports whether the feature gate is on; when it
+	// is off every other field is inert.
+	DynamicGPUPreemption bool `json:"dynamicGPUPreemption"`
+	// ScaleOut reports whether scheduler-driven scale-out can execute right
+	// now (gate on and the MPIJob CRD served).
+	ScaleOut bool `json:"scaleOut"`
+	// Retrieve reports whether retraction/scale-in plans can execute.
+	Retrieve bool `json:"retrieve"`
+	// ModelSource is where throughput profiles come from.
+	ModelSource string `json:"modelSource,omitempty"`
+	// Adapters lists the elastic job adapters this scheduler can drive.
+	Adapters []string `json:"adapters,omitempty"`
+	// ScalePolicyConfigured reports whether a scale permission policy
+	// restricts which workloads may be scaled.
+	ScalePolicyConfigured bool `json:"scalePolicyConfigured"`
+	// ShadowPolicyConfigured reports whether a shadow eviction policy is
+	// being evaluated.
+	ShadowPolicyConfigured bool `json:"shadowPolicyConfigured"`
+	// BackfillBudgetPercent is the configured backfill occupancy cap.
+	BackfillBudgetPercent int64 `json:"backfillBudgetPercent,omitempty"`
+	// MaxWorkerReplicas is the configured per-job scale-out ceiling.
+	MaxWorkerReplicas int64 `json:"maxWorkerReplicas,omitempty"`
+}
+
+// CurrentCapabilities assembles the live capability set from the feature
+// gate, the loaded args, and the CRD availability probe.
+func CurrentCapabilities() Capabilities {
+	capabilities := Capabilities{
+		DynamicGPUPreemption: utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption),
+	}
+	if !capabilities.DynamicGPUPreemption {
+		return capabilities
+	}
+	capabilities.ScaleOut = ElasticJobsAvailable()
+	// Retraction works without the CRD, so retrieve stays on in degraded
+	// mode; only its scale-in half is lost.
+	capabilities.Retrieve = true
+	capabilities.ModelSource = dynamicArgs().ModelSource
+	capabilities.Adapters = append(capabilities.Adapters, mpiJobAdapter.Name, rayClusterAdapter.Name)
+	for _, mapping := range dynamicArgs().ElasticJobMappings {
+		capabilities.Adapters = append(capabilities.Adapters, mapping.Name)
+	}
+	capabilities.ScalePolicyConfigured = dynamicArgs().ScalePolicy != nil
+	capabilities.ShadowPolicyConfigured = dynamicArgs().ShadowPolicy != nil
+	capabilities.BackfillBudgetPercent = dynamicArgs().BackfillBudgetPercent
+	capabilities.MaxWorkerReplicas = dynamicArgs().MaxWorkerReplicas
+	return capabilities
+}
+
+// CapabilitiesHandler serves CurrentCapabilities as JSON.
+func CapabilitiesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(CurrentCapabilities())
+	})
+}
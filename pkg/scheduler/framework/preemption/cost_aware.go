@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// NodeCostLabel prices a node's GPUs, e.g. set by infrastructure tooling to
+// the hourly spot or on-demand rate. Unlabeled nodes cost 1.0, so clusters
+// without cost labels behave exactly as before.
+const NodeCostLabel = "scheduler.alpha/cost-per-gpu-hour"
+
+// nodeCost reads the node's cost label, defaulting to 1.0.
+func nodeCost(node *v1.Node) float64 {
+	costStr, check := node.Labels[NodeCostLabel]
+	if !check {
+		return 1.0
+	}
+	cost, err := strconv.ParseFloat(costStr, 64)
+	if err != nil || cost <= 0 {
+		klog.Infof("Ignoring malformed %v on %v: %q", NodeCostLabel, node.Name, costStr)
+		return 1.0
+	}
+	return cost
+}
+
+// jobCostPerGPUHour averages the cost of the nodes currently hosting the
+// job's pods, which is where additional workers are most likely to land.
+// Jobs with no located pods cost 1.0.
+func (ev *Evaluator) jobCostPerGPUHour(ctx context.Context, nodes *v1.NodeList, MPIJobName string) float64 {
+	var total float64
+	var count int
+	for _, node := range nodes.Items {
+		if ctx.Err() != nil {
+			break
+		}
+		pods, err := ev.podsOnNode(ctx, node.Name)
+		if err != nil {
+			continue
+		}
+		for _, pod := range pods {
+			if strings.HasPrefix(pod.Name, MPIJobName+"-") {
+				total += nodeCost(&node)
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 1.0
+	}
+	return total / float64(count)
+}
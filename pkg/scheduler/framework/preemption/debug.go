@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// maxRecordedDecisions bounds the decision history kept for the debug
+// endpoint.
+const maxRecordedDecisions = 50
+
+// DynamicDecision is one recorded outcome of the Dynamic PostFilter path.
+type DynamicDecision struct {
+	Time    time.Time `json:"time"`
+	Pod     string    `json:"pod"`
+	Outcome string    `json:"outcome"`
+	Message string    `json:"message,omitempty"`
+}
+
+// debugAction is the serializable view of one retrieve-plan action.
+type debugAction struct {
+	Retract    bool   `json:"retract"`
+	VictimPod  string `json:"victimPod,omitempty"`
+	MPIJobName string `json:"mpiJobName,omitempty"`
+	GPUsFreed  int    `json:"gpusFreed"`
+}
+
+// debugPlan is the serializable view of the last computed retrieve plan.
+type debugPlan struct {
+	Time      time.Time     `json:"time"`
+	Preemptor string        `json:"preemptor"`
+	Feasible  bool          `json:"feasible"`
+	NeedGPUs  int           `json:"needGPUs"`
+	GPUsFreed int           `json:"gpusFreed"`
+	Actions   []debugAction `json:"actions"`
+}
+
+// debugState collects what the Dynamic path last saw and decided, for the
+// /debug/dynamic endpoint. Everything here is best-effort observability;
+// nothing reads it back into scheduling decisions.
+type debugState struct {
+	lock            sync.Mutex
+	idleGPUsPerNode map[string]int64
+	elasticJobs     []string
+	lastPlan        *debugPlan
+	decisions       []DynamicDecision
+}
+
+var dynamicDebug = debugState{idleGPUsPerNode: make(map[string]int64)}
+
+// recordIdleGPUs remembers the idle GPU count of one node.
+func recordIdleGPUs(nodeName string, idle int64) {
+	dynamicDebug.lock.Lock()
+	defer dynamicDebug.lock.Unlock()
+	dynamicDebug.idleGPUsPerNode[nodeName] = idle
+}
+
+// recordElasticJobs remembers the elastic jobs the last planning pass found.
+func recordElasticJobs(jobs []string) {
+	dynamicDebug.lock.Lock()
+	defer dynamicDebug.lock.Unlock()
+	dynamicDebug.elasticJobs = append([]string(nil), jobs...)
+}
+
+// recordPlan remembers the last computed retrieve plan.
+func recordPlan(preemptor *v1.Pod, plan *RetrievePlan) {
+	snapshot := &debugPlan{
+		Time:      time.Now(),
+		Preemptor: preemptor.Namespace + "/" + preemptor.Name,
+		Feasible:  plan.Feasible,
+		NeedGPUs:  plan.NeedGPUs,
+		GPUsFreed: plan.GPUsFreed,
+	}
+	for _, action := range plan.Actions {
+		debugAct := debugAction{Retract: action.Retract, MPIJobName: action.MPIJobName, GPUsFreed: action.GPUsFreed}
+		if action.VictimPod != nil {
+			debugAct.VictimPod = action.VictimPod.Namespace + "/" + action.VictimPod.Name
+		}
+		snapshot.Actions = append(snapshot.Actions, debugAct)
+	}
+	dynamicDebug.lock.Lock()
+	defer dynamicDebug.lock.Unlock()
+	dynamicDebug.lastPlan = snapshot
+}
+
+// RecordDynamicDecision appends one Dynamic PostFilter outcome to the
+// bounded decision history.
+func RecordDynamicDecision(pod *v1.Pod, outcome string, message string) {
+	dynamicDebug.lock.Lock()
+	defer dynamicDebug.lock.Unlock()
+	dynamicDebug.decisions = append(dynamicDebug.decisions, DynamicDecision{
+		Time:    time.Now(),
+		Pod:     pod.Namespace + "/" + pod.Name,
+		Outcome: outcome,
+		Message: message,
+	})
+	if len(dynamicDebug.decisions) > maxRecordedDecisions {
+		dynamicDebug.decisions = dynamicDebug.decisions[len(dynamicDebug.decisions)-maxRecordedDecisions:]
+	}
+}
+
+// DebugHandler serves the Dynamic decision state as JSON: idle GPUs per
+// node, discovered elastic jobs, the last retrieve plan, and the last
+// decisions, newest last.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		dynamicDebug.lock.Lock()
+		dump := struct {
+			IdleGPUsPerNode map[string]int64  `json:"idleGPUsPerNode"`
+			ElasticJobs     []string          `json:"elasticJobs"`
+			DegradedMode    bool              `json:"degradedMode"`
+			LastPlan        *debugPlan        `json:"lastPlan,omitempty"`
+			Decisions       []DynamicDecision `json:"decisions"`
+		}{
+			IdleGPUsPerNode: dynamicDebug.idleGPUsPerNode,
+			ElasticJobs:     dynamicDebug.elasticJobs,
+			DegradedMode:    !ElasticJobsAvailable(),
+			LastPlan:        dynamicDebug.lastPlan,
+			Decisions:       dynamicDebug.decisions,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(dump)
+		dynamicDebug.lock.Unlock()
+	})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// schedulingDecisionGVR locates the namespaced SchedulingDecision audit CRD.
+// One object is written per executed Dynamic decision, so SREs can answer
+// "why was my job shrunk" from the API after the fact instead of from logs.
+var schedulingDecisionGVR = schema.GroupVersionResource{
+	Group:    "audit.scheduling.k8s.io",
+	Version:  "v1alpha1",
+	Resource: "schedulingdecisions",
+}
+
+// decisionGCInterval is how often expired SchedulingDecision objects are
+// collected.
+const decisionGCInterval = 10 * time.Minute
+
+// recordSchedulingDecision persists one executed decision in the preemptor's
+// namespace. The audit trail is best effort: a missing CRD or a write error
+// is logged and never blocks the scheduling cycle. Simulation records
+// nothing.
+func (ev *Evaluator) recordSchedulingDecision(ctx context.Context, planUID string, preemptor *v1.Pod, action string, plan *RetrievePlan, scaleOuts []scaleOutAllocation) {
+	if ev.Snapshot != nil || dynamicArgs().DecisionTTLMinutes == 0 {
+		return
+	}
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		klog.Infof("Failed to create dynamic client for decision audit: %v", err)
+		return
+	}
+	spec := map[string]interface{}{
+		"preemptor": preemptor.Namespace + "/" + preemptor.Name,
+		"action":    action,
+		"decidedAt": time.Now().Format(time.RFC3339),
+	}
+	if plan != nil {
+		var victims []interface{}
+		var scaleIns []interface{}
+		for _, planAction := range plan.Actions {
+			if planAction.Retract {
+				victims = append(victims, planAction.VictimPod.Namespace+"/"+planAction.VictimPod.Name)
+			} else {
+				scaleIns = append(scaleIns, map[string]interface{}{"job": planAction.MPIJobName, "gpusFreed": int64(planAction.GPUsFreed)})
+			}
+		}
+		if len(victims) > 0 {
+			spec["victims"] = victims
+		}
+		if len(scaleIns) > 0 {
+			spec["scaleIns"] = scaleIns
+		}
+		spec["needGPUs"] = int64(plan.NeedGPUs)
+		spec["gpusFreed"] = int64(plan.GPUsFreed)
+		spec["throughputLoss"] = int64(plan.ThroughputLoss)
+	}
+	if len(scaleOuts) > 0 {
+		var grown []interface{}
+		for _, allocation := range scaleOuts {
+			grown = append(grown, map[string]interface{}{"job": allocation.name, "delta": allocation.delta})
+		}
+		spec["scaleOuts"] = grown
+	}
+	decision := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": schedulingDecisionGVR.Group + "/" + schedulingDecisionGVR.Version,
+		"kind":       "SchedulingDecision",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%s-%s", preemptor.Name, strings.Split(planUID, "-")[0]),
+			"namespace": preemptor.Namespace,
+		},
+		"spec": spec,
+	}}
+	if _, err := dynamicClient.Resource(schedulingDecisionGVR).Namespace(preemptor.Namespace).Create(ctx, decision, metav1.CreateOptions{}); err != nil {
+		klog.Infof("Failed to record SchedulingDecision for %v: %v", preemptor.Name, err)
+	}
+}
+
+// RunDecisionGC periodically deletes SchedulingDecision objects older than
+// the configured TTL, until ctx is done.
+func (ev *Evaluator) RunDecisionGC(ctx context.Context) {
+	wait.UntilWithContext(ctx, ev.decisionGCSweep, decisionGCInterval)
+}
+
+func (ev *Evaluator) decisionGCSweep(ctx context.Context) {
+	if dynamicArgs().DecisionTTLMinutes == 0 {
+		return
+	}
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		klog.Infof("Failed to create dynamic client for decision GC: %v", err)
+		return
+	}
+	decisions, err := dynamicClient.Resource(schedulingDecisionGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Failed to list SchedulingDecisions: %v", err)
+		return
+	}
+	ttl := time.Duration(dynamicArgs().DecisionTTLMinutes) * time.Minute
+	for _, decision := range decisions.Items {
+		if time.Since(decision.GetCreationTimestamp().Time) < ttl {
+			continue
+		}
+		if err := dynamicClient.Resource(schedulingDecisionGVR).Namespace(decision.GetNamespace()).Delete(ctx, decision.GetName(), metav1.DeleteOptions{}); err != nil {
+			klog.Infof("Failed to delete expired SchedulingDecision %v: %v", decision.GetName(), err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// dynamicStatusMessage renders an unschedulable verdict the way it should
+// read in `kubectl describe pod`: which path ran, the cycle's headline
+// numbers and how close the attempted plan came, instead of a bare phrase
+// like "Scale-Out MPIJob".
+func dynamicStatusMessage(verdict string, requestGPUs int64, idleGPUs int64, plan *RetrievePlan) string {
+	message := fmt.Sprintf("Dynamic GPU scheduling: %s (%d GPU(s) requested, %d idle)", verdict, requestGPUs, idleGPUs)
+	if plan != nil && !plan.Feasible && len(plan.Actions) > 0 {
+		message += fmt.Sprintf("; best retrieve plan freed %d of %d needed GPU(s) in %d action(s)", plan.GPUsFreed, plan.NeedGPUs, len(plan.Actions))
+	}
+	return message
+}
+
+// gpuShortageDiagnostics explains an unschedulable verdict in the register
+// of FitError aggregates: how many GPUs were asked for, where idle ones
+// are, how many backfilled pods exist and how close the best plan came to
+// freeing enough. It replaces the old "Nothing can do", which left
+// operators to reconstruct the cluster state from logs.
+func (ev *Evaluator) gpuShortageDiagnostics(ctx context.Context, nodes *v1.NodeList, plan *RetrievePlan, requestGPUs int64, idleGPUs int64) string {
+	var gpuNodes, nodesWithIdle, backfilledPods int
+	for _, node := range nodes.Items {
+		capacity := nodeAcceleratorCapacity(&node)
+		if capacity == 0 {
+			continue
+		}
+		gpuNodes++
+		pods, err := ev.podsOnNode(ctx, node.Name)
+		if err != nil {
+			continue
+		}
+		var used int64
+		for _, pod := range pods {
+			used += podAcceleratorRequest(pod)
+			if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "backfilled" {
+				backfilledPods++
+			}
+		}
+		if capacity > used {
+			nodesWithIdle++
+		}
+	}
+	message := fmt.Sprintf("%d GPU(s) requested, %d idle across %d GPU node(s) (%d with idle GPUs), %d backfilled pod(s) present", requestGPUs, idleGPUs, gpuNodes, nodesWithIdle, backfilledPods)
+	if plan != nil && !plan.Feasible {
+		message += fmt.Sprintf("; best plan frees %d of %d needed GPU(s) in %d action(s)", plan.GPUsFreed, plan.NeedGPUs, len(plan.Actions))
+	}
+	return message
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/dynamic-resource-allocation/resourceclaim"
+	"k8s.io/klog/v2"
+)
+
+// gpuResourceClass reports whether the DRA resource class provisions GPUs,
+// per the configured class names.
+func gpuResourceClass(className string) bool {
+	for _, name := range dynamicArgs().GPUResourceClassNames {
+		if className == name {
+			return true
+		}
+	}
+	return false
+}
+
+// podDRAGPUs counts the GPUs the pod consumes through DRA ResourceClaims
+// instead of extended resource requests. Each claim against a GPU resource
+// class counts as one device, matching how migration-era GPU drivers
+// allocate one device per claim. Claims that cannot be resolved count as
+// zero; undercounting keeps the accounting conservative.
+func (ev *Evaluator) podDRAGPUs(ctx context.Context, pod *v1.Pod) int64 {
+	if len(pod.Spec.ResourceClaims) == 0 || len(dynamicArgs().GPUResourceClassNames) == 0 {
+		return 0
+	}
+	// Offline simulation has no API to resolve claims against.
+	if ev.Handler == nil {
+		return 0
+	}
+	var count int64
+	for i := range pod.Spec.ResourceClaims {
+		claimName, _, err := resourceclaim.Name(pod, &pod.Spec.ResourceClaims[i])
+		if err != nil || claimName == nil {
+			continue
+		}
+		claim, err := ev.Handler.ClientSet().ResourceV1alpha2().ResourceClaims(pod.Namespace).Get(ctx, *claimName, metav1.GetOptions{})
+		if err != nil {
+			klog.Infof("Failed to get ResourceClaim %v/%v: %v", pod.Namespace, *claimName, err)
+			continue
+		}
+		if gpuResourceClass(claim.Spec.ResourceClassName) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,422 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+	"sigs.k8s.io/yaml"
+)
+
+// DynamicPreemptionArgs configures the Dynamic GPU preemption path. It
+// replaces the constants that used to be hardcoded throughout this package.
+type DynamicPreemptionArgs struct {
+	// Namespace holds the MPIJobs the scheduler may scale.
+	Namespace string `json:"namespace,omitempty"`
+	// GPUResourceNames are the extended resources the elastic machinery
+	// accounts for: GPUs, FPGAs, SmartNICs or custom accelerators. All
+	// entries are summed for idle accounting; the first entry is the primary
+	// resource used where a single name is needed, e.g. resource-qualified
+	// throughput profile lookups.
+	GPUResourceNames []string `json:"gpuResourceNames,omitempty"`
+	// GPUResourceClassNames are the DRA resource classes treated as GPUs, so
+	// pods consuming GPUs through ResourceClaims instead of extended
+	// resource requests stay visible to GPU accounting and victim selection.
+	GPUResourceClassNames []string `json:"gpuResourceClassNames,omitempty"`
+	// MaxWorkerReplicas caps how far a single job may be scaled out.
+	MaxWorkerReplicas int64 `json:"maxWorkerReplicas,omitempty"`
+	// ElasticBackoffSeconds is the per-pod cooldown between executed plans.
+	ElasticBackoffSeconds int64 `json:"elasticBackoffSeconds,omitempty"`
+	// PlanningDeadlineSeconds bounds how long one elastic planning pass may
+	// spend on API calls before the pod is reported unschedulable, so a slow
+	// apiserver cannot hang PostFilter.
+	PlanningDeadlineSeconds int64 `json:"planningDeadlineSeconds,omitempty"`
+	// ModelSource selects the throughput profiles: "builtin" for the compiled
+	// table, or a path to a YAML file mapping model name to throughput per
+	// worker count.
+	ModelSource string `json:"modelSource,omitempty"`
+	// ElasticJobMappings declares additional scale-capable custom resources
+	// without writing a Go adapter per CRD.
+	ElasticJobMappings []ElasticJobMapping `json:"elasticJobMappings,omitempty"`
+	// BackfillBudgetPercent caps the GPUs backfill may occupy, as a
+	// percentage of the pending queue's GPU demand. Backfilling far beyond
+	// queued demand only sets up future retraction storms.
+	BackfillBudgetPercent int64 `json:"backfillBudgetPercent,omitempty"`
+	// BackfillPermitWaitSeconds bounds how long a backfilled pod is held at
+	// Permit before it binds. The hold normally ends earlier, when a Dynamic
+	// cycle settles its retraction decision; the timer is only the fallback
+	// for clusters where no such cycle runs.
+	BackfillPermitWaitSeconds int64 `json:"backfillPermitWaitSeconds,omitempty"`
+	// MPIJobBoost configures the queue-sort preference for pods whose MPIJob
+	// already has pods running on a node.
+	MPIJobBoost util.MPIJobBoostConfig `json:"mpiJobBoost,omitempty"`
+	// NotReadyForceDeleteSeconds, when positive, lets victims on a NotReady
+	// node be force-deleted once the node has been NotReady at least this
+	// long. Only enable this on clusters that fence dead nodes; without
+	// fencing the pod may still be running. Zero keeps NotReady nodes out of
+	// victim selection entirely.
+	NotReadyForceDeleteSeconds int64 `json:"notReadyForceDeleteSeconds,omitempty"`
+	// CostAwareScaleOut, when true, divides each candidate's marginal
+	// throughput by the average cost of the nodes hosting it, so
+	// throughput-per-dollar drives which job grows. Node prices come from
+	// the scheduler.alpha/cost-per-gpu-hour node label.
+	CostAwareScaleOut bool `json:"costAwareScaleOut,omitempty"`
+	// MaxScaleOutStep caps how many workers one scale-out decision may add
+	// to a job. The default of 1 keeps the historical one-worker increments.
+	MaxScaleOutStep int64 `json:"maxScaleOutStep,omitempty"`
+	// BatchScaleOut, when true, distributes all idle GPUs across eligible
+	// jobs in one pass by marginal throughput, executed as a batch, instead
+	// of growing one job by one step per scheduling cycle.
+	BatchScaleOut bool `json:"batchScaleOut,omitempty"`
+	// ScaleOutPolicy selects which eligible job absorbs idle GPUs:
+	// "max-throughput" (the historical greedy choice) or "drf" for
+	// dominant-resource-fair selection across owners.
+	ScaleOutPolicy string `json:"scaleOutPolicy,omitempty"`
+	// ScaleOutColocation restricts where a scale-out worker may land: ""
+	// (anywhere passing the other constraints), "node" (only nodes already
+	// hosting the job's workers) or "zone" (only the topology zones the job
+	// already runs in). With it set, replicas are only incremented when such
+	// a node has an idle GPU.
+	ScaleOutColocation string `json:"scaleOutColocation,omitempty"`
+	// ProfilePolicies overrides parts of the Dynamic policy per scheduler
+	// profile, keyed by profile name. Pods scheduled under a profile without
+	// an entry use the global settings.
+	ProfilePolicies map[string]DynamicProfilePolicy `json:"profilePolicies,omitempty"`
+	// ElasticJobSelector, when set, is a label selector (e.g.
+	// "scheduling.k8s.io/elastic=true") identifying the pods of jobs the
+	// scheduler may scale. Empty keeps the historical *-elastic-* pod name
+	// pattern.
+	ElasticJobSelector string `json:"elasticJobSelector,omitempty"`
+	// UnknownModelPolicy says what to do with workloads whose model-name is
+	// absent from the throughput table: "skip" treats them as non-scalable,
+	// "linear" assumes one throughput unit per worker, "unschedulable"
+	// reports the pending pod unschedulable with a clear message.
+	UnknownModelPolicy string `json:"unknownModelPolicy,omitempty"`
+	// AsyncScaleExecutor, when true, moves MPIJob update calls out of the
+	// scheduling cycle into a rate-limited work queue with retries, so a
+	// slow API server cannot stall PostFilter.
+	AsyncScaleExecutor bool `json:"asyncScaleExecutor,omitempty"`
+	// WaitForEffectSeconds, when positive, holds the preemptor's nomination
+	// after an executed plan until the retracted victims are actually
+	// deleted, up to this many seconds, so bind attempts do not race kubelet
+	// device reclamation. Zero nominates immediately.
+	WaitForEffectSeconds int64 `json:"waitForEffectSeconds,omitempty"`
+	// UtilizationMetricsURL, when set, is the base URL of a Prometheus-
+	// compatible endpoint scraping the DCGM exporter (e.g.
+	// "http://prometheus.monitoring:9090"). Scale-out then prefers jobs that
+	// are actually compute-bound and retraction prefers victims idling their
+	// GPUs. Empty keeps planning on the throughput table alone.
+	UtilizationMetricsURL string `json:"utilizationMetricsURL,omitempty"`
+	// MutationQPS and MutationBurst, when MutationQPS is positive, put a
+	// cluster-wide token bucket over scale and retract API writes. A zero
+	// burst means one token.
+	MutationQPS   float64 `json:"mutationQPS,omitempty"`
+	MutationBurst int64   `json:"mutationBurst,omitempty"`
+	// JobMutationQPS and JobMutationBurst do the same per job.
+	JobMutationQPS   float64 `json:"jobMutationQPS,omitempty"`
+	JobMutationBurst int64   `json:"jobMutationBurst,omitempty"`
+	// DecisionTTLMinutes, when positive, persists every executed decision as
+	// a namespaced SchedulingDecision audit object and garbage-collects
+	// objects older than this TTL. Zero disables the audit trail.
+	DecisionTTLMinutes int64 `json:"decisionTTLMinutes,omitempty"`
+	// VictimSortOrder, when set, lists the dimensions ranking retraction
+	// and scale-in candidates, weakest tie-break first: "deadline",
+	// "runtime", "cost", "gpus", "priority", "pdb". Empty keeps the default
+	// order, which retracts shortest-running pods among equally cheap ones.
+	VictimSortOrder []string `json:"victimSortOrder,omitempty"`
+	// FairShares, when set, turns on fair queueing for GPU pods: it maps a
+	// tenant to its share weight, and the queue sort dequeues the tenant
+	// furthest under its share first. Tenants without an entry weigh 1.
+	FairShares map[string]int64 `json:"fairShares,omitempty"`
+	// FairShareLabel optionally names the pod label identifying the tenant;
+	// empty groups tenants by namespace.
+	FairShareLabel string `json:"fairShareLabel,omitempty"`
+	// KueueIntegration, when true, defers to Kueue: only jobs with an
+	// admitted Workload are scaled, and victims are reclaimed by
+	// deactivating their Workload instead of deleting pods, so the two
+	// systems do not fight over capacity.
+	KueueIntegration bool `json:"kueueIntegration,omitempty"`
+	// RetrievePolicy decides between retracting backfilled pods and
+	// shrinking scaled-out jobs when both could free enough GPUs:
+	// "prefer-retract", "prefer-scale-in", "retract-only", "scale-in-only".
+	// Empty mixes the two purely by eviction cost, the historical behavior.
+	RetrievePolicy string `json:"retrievePolicy,omitempty"`
+	// VictimObjective, when set, selects a registered CandidateObjective to
+	// rank victims instead of the built-in lexicographic order, so selection
+	// experiments plug in without forking the planner.
+	VictimObjective string `json:"victimObjective,omitempty"`
+	// ProvisioningClassName, when set, names the autoscaling class a
+	// ProvisioningRequest is created with (e.g.
+	// "check-capacity.autoscaling.x-k8s.io") whenever no elastic action can
+	// free enough GPUs, so the cluster autoscaler adds GPU nodes. Empty
+	// never asks for new capacity.
+	ProvisioningClassName string `json:"provisioningClassName,omitempty"`
+	// ScalePolicy, when set, restricts which namespaces and jobs the
+	// scheduler may scale; workloads outside the policy are never mutated.
+	ScalePolicy *ScalePermissionPolicy `json:"scalePolicy,omitempty"`
+	// NamespaceScoped, when true, restricts the Dynamic path to the
+	// preemptor's own namespace: only its backfilled pods are retraction
+	// victims and elastic jobs are only scaled when they live there too.
+	// For multi-tenant clusters where cross-namespace retraction is not
+	// acceptable.
+	NamespaceScoped bool `json:"namespaceScoped,omitempty"`
+	// AnnotationKeys overrides the keys of the annotations the scheduler
+	// consumes ("model-name", "scheduling-state", "scale-out",
+	// "retract-check-var"), so deployments with existing annotation naming
+	// conventions need not relabel their workloads. Empty fields keep the
+	// defaults.
+	AnnotationKeys util.AnnotationKeys `json:"annotationKeys,omitempty"`
+	// ShadowPolicy, when set, computes a second plan per elastic attempt
+	// under these eviction cost weights. Shadow plans are never executed;
+	// divergence from the active plan is reported via metrics so policy
+	// changes can be evaluated safely in production.
+	ShadowPolicy *EvictionCostWeights `json:"shadowPolicy,omitempty"`
+}
+
+// ElasticJobMapping declares how to scale one custom resource kind. Field
+// paths are dot-separated; numeric segments index into lists.
+type ElasticJobMapping struct {
+	// Name identifies the mapping in logs and adapter lookups.
+	Name string `json:"name"`
+	// Group, Version and Resource locate the custom resource.
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	// ReplicasFieldPath is the path to the worker replica count.
+	ReplicasFieldPath string `json:"replicasFieldPath"`
+	// StatusReadyPath optionally points at the ready worker count.
+	StatusReadyPath string `json:"statusReadyPath,omitempty"`
+	// MinReplicasFieldPath and MaxReplicasFieldPath optionally point at the
+	// worker bounds the workload declares; unset paths fall back to the
+	// shared bounds annotations.
+	MinReplicasFieldPath string `json:"minReplicasFieldPath,omitempty"`
+	MaxReplicasFieldPath string `json:"maxReplicasFieldPath,omitempty"`
+	// AnnotationsPath optionally overrides metadata.annotations.
+	AnnotationsPath string `json:"annotationsPath,omitempty"`
+}
+
+// NewDynamicPreemptionArgs returns the defaults matching the previously
+// hardcoded behavior.
+func NewDynamicPreemptionArgs() *DynamicPreemptionArgs {
+	return &DynamicPreemptionArgs{
+		Namespace:                 "my-ns",
+		GPUResourceNames:          []string{"nvidia.com/gpu"},
+		GPUResourceClassNames:     []string{"gpu.nvidia.com"},
+		MaxWorkerReplicas:         5,
+		ElasticBackoffSeconds:     30,
+		PlanningDeadlineSeconds:   10,
+		ModelSource:               "builtin",
+		BackfillBudgetPercent:     100,
+		BackfillPermitWaitSeconds: 10,
+		MaxScaleOutStep:           1,
+		ScaleOutPolicy:            ScaleOutPolicyMaxThroughput,
+		UnknownModelPolicy:        UnknownModelSkip,
+	}
+}
+
+// Validate checks the args for consistency.
+func (args *DynamicPreemptionArgs) Validate() error {
+	if args.Namespace == "" {
+		return fmt.Errorf("namespace must not be empty")
+	}
+	if len(args.GPUResourceNames) == 0 {
+		return fmt.Errorf("gpuResourceNames must contain at least one resource")
+	}
+	if args.MaxWorkerReplicas <= 0 {
+		return fmt.Errorf("maxWorkerReplicas must be positive, got %d", args.MaxWorkerReplicas)
+	}
+	if args.ElasticBackoffSeconds < 0 {
+		return fmt.Errorf("elasticBackoffSeconds must not be negative, got %d", args.ElasticBackoffSeconds)
+	}
+	if args.PlanningDeadlineSeconds <= 0 {
+		return fmt.Errorf("planningDeadlineSeconds must be positive, got %d", args.PlanningDeadlineSeconds)
+	}
+	if args.ModelSource == "" {
+		return fmt.Errorf("modelSource must not be empty")
+	}
+	if args.BackfillBudgetPercent <= 0 {
+		return fmt.Errorf("backfillBudgetPercent must be positive, got %d", args.BackfillBudgetPercent)
+	}
+	if args.BackfillPermitWaitSeconds <= 0 {
+		return fmt.Errorf("backfillPermitWaitSeconds must be positive, got %d", args.BackfillPermitWaitSeconds)
+	}
+	if args.MaxScaleOutStep <= 0 {
+		return fmt.Errorf("maxScaleOutStep must be positive, got %d", args.MaxScaleOutStep)
+	}
+	if args.ScaleOutPolicy != ScaleOutPolicyMaxThroughput && args.ScaleOutPolicy != ScaleOutPolicyDRF {
+		return fmt.Errorf("scaleOutPolicy must be %q or %q, got %q", ScaleOutPolicyMaxThroughput, ScaleOutPolicyDRF, args.ScaleOutPolicy)
+	}
+	if args.ElasticJobSelector != "" {
+		if _, err := labels.Parse(args.ElasticJobSelector); err != nil {
+			return fmt.Errorf("elasticJobSelector: %v", err)
+		}
+	}
+	if args.UnknownModelPolicy != UnknownModelSkip && args.UnknownModelPolicy != UnknownModelLinear && args.UnknownModelPolicy != UnknownModelUnschedulable {
+		return fmt.Errorf("unknownModelPolicy must be %q, %q or %q, got %q", UnknownModelSkip, UnknownModelLinear, UnknownModelUnschedulable, args.UnknownModelPolicy)
+	}
+	if args.ScaleOutColocation != "" && args.ScaleOutColocation != ColocationNode && args.ScaleOutColocation != ColocationZone {
+		return fmt.Errorf("scaleOutColocation must be empty, %q or %q, got %q", ColocationNode, ColocationZone, args.ScaleOutColocation)
+	}
+	for profile, policy := range args.ProfilePolicies {
+		if !profilePresetKnown(policy.Preset) {
+			return fmt.Errorf("profilePolicies[%q]: preset must be empty, %q, %q or %q, got %q", profile, ProfilePresetAggressive, ProfilePresetConservative, ProfilePresetRetractOnly, policy.Preset)
+		}
+		if policy.ScaleOutPolicy != "" && policy.ScaleOutPolicy != ScaleOutPolicyMaxThroughput && policy.ScaleOutPolicy != ScaleOutPolicyDRF {
+			return fmt.Errorf("profilePolicies[%q]: scaleOutPolicy must be %q or %q, got %q", profile, ScaleOutPolicyMaxThroughput, ScaleOutPolicyDRF, policy.ScaleOutPolicy)
+		}
+	}
+	for _, name := range args.VictimSortOrder {
+		if !victimSortDimensionKnown(name) {
+			return fmt.Errorf("victimSortOrder: unknown dimension %q", name)
+		}
+	}
+	for tenant, weight := range args.FairShares {
+		if weight <= 0 {
+			return fmt.Errorf("fairShares[%q]: weight must be positive, got %d", tenant, weight)
+		}
+	}
+	switch args.RetrievePolicy {
+	case "", RetrievePolicyPreferRetract, RetrievePolicyPreferScaleIn, RetrievePolicyRetractOnly, RetrievePolicyScaleInOnly:
+	default:
+		return fmt.Errorf("retrievePolicy must be empty, %q, %q, %q or %q, got %q", RetrievePolicyPreferRetract, RetrievePolicyPreferScaleIn, RetrievePolicyRetractOnly, RetrievePolicyScaleInOnly, args.RetrievePolicy)
+	}
+	if !candidateObjectiveKnown(args.VictimObjective) {
+		return fmt.Errorf("victimObjective: no objective registered under %q", args.VictimObjective)
+	}
+	if args.WaitForEffectSeconds < 0 {
+		return fmt.Errorf("waitForEffectSeconds must not be negative, got %d", args.WaitForEffectSeconds)
+	}
+	if args.MutationQPS < 0 || args.MutationBurst < 0 {
+		return fmt.Errorf("mutationQPS and mutationBurst must not be negative")
+	}
+	if args.JobMutationQPS < 0 || args.JobMutationBurst < 0 {
+		return fmt.Errorf("jobMutationQPS and jobMutationBurst must not be negative")
+	}
+	if args.DecisionTTLMinutes < 0 {
+		return fmt.Errorf("decisionTTLMinutes must not be negative, got %d", args.DecisionTTLMinutes)
+	}
+	if args.NotReadyForceDeleteSeconds < 0 {
+		return fmt.Errorf("notReadyForceDeleteSeconds must not be negative, got %d", args.NotReadyForceDeleteSeconds)
+	}
+	for i, mapping := range args.ElasticJobMappings {
+		if mapping.Name == "" {
+			return fmt.Errorf("elasticJobMappings[%d]: name must not be empty", i)
+		}
+		if mapping.Version == "" || mapping.Resource == "" {
+			return fmt.Errorf("elasticJobMappings[%d] (%s): version and resource must not be empty", i, mapping.Name)
+		}
+		if mapping.ReplicasFieldPath == "" {
+			return fmt.Errorf("elasticJobMappings[%d] (%s): replicasFieldPath must not be empty", i, mapping.Name)
+		}
+	}
+	return nil
+}
+
+// activeDynamicArgs holds the active configuration behind an atomic
+// pointer: informer handlers and background sweepers read it while a later
+// profile's New may still be installing its args.
+var activeDynamicArgs atomic.Pointer[DynamicPreemptionArgs]
+
+func init() {
+	activeDynamicArgs.Store(NewDynamicPreemptionArgs())
+}
+
+// dynamicArgs returns the active configuration.
+func dynamicArgs() *DynamicPreemptionArgs {
+	return activeDynamicArgs.Load()
+}
+
+// loadedScalableModelData holds profiles loaded from a ModelSource file, nil
+// when the builtin table is used.
+var loadedScalableModelData map[string][]float64
+
+// SetDynamicPreemptionArgs installs validated args and loads the throughput
+// profiles from the configured source.
+func SetDynamicPreemptionArgs(args *DynamicPreemptionArgs) error {
+	if err := args.Validate(); err != nil {
+		return err
+	}
+	if args.ModelSource != "builtin" {
+		raw, err := os.ReadFile(args.ModelSource)
+		if err != nil {
+			return fmt.Errorf("reading model source %q: %v", args.ModelSource, err)
+		}
+		profiles := make(map[string][]float64)
+		if err := yaml.Unmarshal(raw, &profiles); err != nil {
+			return fmt.Errorf("parsing model source %q: %v", args.ModelSource, err)
+		}
+		loadedScalableModelData = profiles
+	} else {
+		loadedScalableModelData = nil
+	}
+	activeDynamicArgs.Store(args)
+	resetMutationLimiters()
+	util.SetAnnotationKeys(args.AnnotationKeys)
+	util.SetElasticQueueConfig(args.Namespace, args.MPIJobBoost)
+	util.SetFairShareConfig(args.FairShares, args.FairShareLabel, args.GPUResourceNames)
+	klog.Infof("Dynamic preemption args: %+v", *args)
+	return nil
+}
+
+// BackfillBudgetPercent exposes the configured backfill occupancy cap to the
+// Backfill plugin.
+func BackfillBudgetPercent() int64 {
+	return dynamicArgs().BackfillBudgetPercent
+}
+
+// BackfillPermitWait exposes the configured Permit hold window the same way.
+func BackfillPermitWait() time.Duration {
+	return time.Duration(dynamicArgs().BackfillPermitWaitSeconds) * time.Second
+}
+
+// gpuResource returns the primary GPU resource name used for accounting.
+func gpuResource() v1.ResourceName {
+	return v1.ResourceName(dynamicArgs().GPUResourceNames[0])
+}
+
+// elasticBackoff returns the configured per-pod plan cooldown.
+func elasticBackoff() time.Duration {
+	return time.Duration(dynamicArgs().ElasticBackoffSeconds) * time.Second
+}
+
+// planningDeadline returns the configured bound on one planning pass.
+func planningDeadline() time.Duration {
+	return time.Duration(dynamicArgs().PlanningDeadlineSeconds) * time.Second
+}
+
+// namespaceInScope reports whether Dynamic may touch workloads in
+// <namespace> on behalf of <preemptor>. Outside namespace-scoped mode
+// everything is in scope.
+func namespaceInScope(preemptor *v1.Pod, namespace string) bool {
+	return !dynamicArgs().NamespaceScoped || namespace == preemptor.Namespace
+}
+
+// activeScalableModelData returns the throughput table from the configured
+// model source.
+func activeScalableModelData() map[string][]float64 {
+	if loadedScalableModelData != nil {
+		return loadedScalableModelData
+	}
+	return defaultScalableModelData
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// elasticCRDProbeInterval is how often the MPIJob CRD presence is re-checked
+// via discovery, so elastic features come back automatically once the CRD is
+// (re)installed without restarting the scheduler.
+const elasticCRDProbeInterval = 30 * time.Second
+
+var (
+	elasticAvailabilityLock sync.RWMutex
+	// elasticCRDAbsent is set while the MPIJob CRD is not served. While set,
+	// elastic scale-out and scale-in are disabled; backfill and pod
+	// retraction keep working since they only touch core resources.
+	elasticCRDAbsent bool
+)
+
+// ElasticJobsAvailable reports whether the MPIJob CRD is served, i.e.
+// whether scale mutations may be planned and executed.
+func ElasticJobsAvailable() bool {
+	elasticAvailabilityLock.RLock()
+	defer elasticAvailabilityLock.RUnlock()
+	return !elasticCRDAbsent
+}
+
+// setElasticCRDAbsent records the degraded mode, logging only on transitions
+// and keeping the elastic_degraded_mode gauge in sync.
+func setElasticCRDAbsent(absent bool) {
+	elasticAvailabilityLock.Lock()
+	defer elasticAvailabilityLock.Unlock()
+	if elasticCRDAbsent == absent {
+		return
+	}
+	elasticCRDAbsent = absent
+	if absent {
+		klog.Infof("MPIJob CRD absent, elastic scale features disabled; backfill and retraction stay active")
+		metrics.ElasticDegradedMode.Set(1)
+	} else {
+		klog.Infof("MPIJob CRD present, elastic scale features enabled")
+		metrics.ElasticDegradedMode.Set(0)
+	}
+}
+
+// markElasticDegradedOnNotFound flips into degraded mode when an error from
+// the dynamic client means the resource type itself is not served, as opposed
+// to a single job instance being gone.
+func markElasticDegradedOnNotFound(err error) {
+	if err == nil || !apierrors.IsNotFound(err) {
+		return
+	}
+	if strings.Contains(err.Error(), "could not find the requested resource") {
+		setElasticCRDAbsent(true)
+	}
+}
+
+// elasticCRDServed asks discovery whether the adapter's resource is served.
+func elasticCRDServed(client discovery.DiscoveryInterface, adapter ElasticJobAdapter) bool {
+	resources, err := client.ServerResourcesForGroupVersion(adapter.GVR.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == adapter.GVR.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// RunElasticCRDProbe keeps the degraded-mode state current by probing the
+// MPIJob CRD via discovery, first at startup and then periodically, until
+// ctx is done.
+func RunElasticCRDProbe(ctx context.Context, client discovery.DiscoveryInterface) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		setElasticCRDAbsent(!elasticCRDServed(client, mpiJobAdapter))
+	}, elasticCRDProbeInterval)
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// ElasticJobAdapter describes how to read and scale the worker replicas of
+// one kind of elastic training workload via the dynamic client, so the
+// throughput-profile and retrieve machinery is not tied to MPIJobs. The
+// replicas path may contain numeric segments that index into lists, e.g. the
+// first Ray worker group.
+type ElasticJobAdapter struct {
+	// Name identifies the adapter in plugin args and logs.
+	Name string
+	// GVR locates the custom resource.
+	GVR schema.GroupVersionResource
+	// ReplicasPath is the field path to the worker replica count.
+	ReplicasPath []string
+	// StatusReadyPath optionally points at the ready worker count.
+	StatusReadyPath []string
+	// MinReplicasPath and MaxReplicasPath point at the worker bounds the job
+	// itself declares; unset paths fall back to the shared bounds
+	// annotations.
+	MinReplicasPath []string
+	MaxReplicasPath []string
+	// AnnotationsPath is where scale-out and audit annotations are recorded,
+	// metadata.annotations when empty.
+	AnnotationsPath []string
+}
+
+// annotationsPath returns the adapter's annotation location, defaulting to
+// metadata.annotations.
+func (adapter ElasticJobAdapter) annotationsPath() []string {
+	if len(adapter.AnnotationsPath) > 0 {
+		return adapter.AnnotationsPath
+	}
+	return []string{"metadata", "annotations"}
+}
+
+var (
+	// mpiJobAdapter scales kubeflow MPIJob worker replicas.
+	mpiJobAdapter = ElasticJobAdapter{
+		Name:            "mpijob",
+		GVR:             schema.GroupVersionResource{Group: "kubeflow.org", Version: "v1", Resource: "mpijobs"},
+		ReplicasPath:    []string{"spec", "mpiReplicaSpecs", "Worker", "replicas"},
+		MinReplicasPath: []string{"spec", "elasticPolicy", "minReplicas"},
+		MaxReplicasPath: []string{"spec", "elasticPolicy", "maxReplicas"},
+	}
+	// rayClusterAdapter scales the first worker group of a RayCluster, which
+	// is where elastic training workers live by convention.
+	rayClusterAdapter = ElasticJobAdapter{
+		Name:            "raycluster",
+		GVR:             schema.GroupVersionResource{Group: "ray.io", Version: "v1", Resource: "rayclusters"},
+		ReplicasPath:    []string{"spec", "workerGroupSpecs", "0", "replicas"},
+		MinReplicasPath: []string{"spec", "workerGroupSpecs", "0", "minReplicas"},
+		MaxReplicasPath: []string{"spec", "workerGroupSpecs", "0", "maxReplicas"},
+	}
+)
+
+// elasticJobAdapterByName resolves a declared mapping or a builtin adapter,
+// with declared mappings taking precedence so operators can override the
+// builtin paths.
+func elasticJobAdapterByName(name string) (ElasticJobAdapter, error) {
+	for _, mapping := range dynamicArgs().ElasticJobMappings {
+		if mapping.Name == name {
+			return adapterFromMapping(mapping), nil
+		}
+	}
+	for _, adapter := range []ElasticJobAdapter{mpiJobAdapter, rayClusterAdapter} {
+		if adapter.Name == name {
+			return adapter, nil
+		}
+	}
+	return ElasticJobAdapter{}, fmt.Errorf("unknown elastic job adapter %q", name)
+}
+
+// splitFieldPath turns a dot-separated field path into its segments.
+func splitFieldPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// adapterFromMapping builds an adapter from a declared CRD-path mapping.
+func adapterFromMapping(mapping ElasticJobMapping) ElasticJobAdapter {
+	return ElasticJobAdapter{
+		Name:            mapping.Name,
+		GVR:             schema.GroupVersionResource{Group: mapping.Group, Version: mapping.Version, Resource: mapping.Resource},
+		ReplicasPath:    splitFieldPath(mapping.ReplicasFieldPath),
+		StatusReadyPath: splitFieldPath(mapping.StatusReadyPath),
+		MinReplicasPath: splitFieldPath(mapping.MinReplicasFieldPath),
+		MaxReplicasPath: splitFieldPath(mapping.MaxReplicasFieldPath),
+		AnnotationsPath: splitFieldPath(mapping.AnnotationsPath),
+	}
+}
+
+// ValidateElasticJobMappings checks every declared mapping against the
+// discovery API so typos in GVRs fail scheduler startup instead of silently
+// producing no-op scale calls.
+func ValidateElasticJobMappings(client discovery.DiscoveryInterface, mappings []ElasticJobMapping) error {
+	for _, mapping := range mappings {
+		gv := schema.GroupVersion{Group: mapping.Group, Version: mapping.Version}
+		resources, err := client.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			return fmt.Errorf("elastic job mapping %q: discovering %v: %v", mapping.Name, gv, err)
+		}
+		found := false
+		for _, apiResource := range resources.APIResources {
+			if apiResource.Name == mapping.Resource {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("elastic job mapping %q: resource %q not served by %v", mapping.Name, mapping.Resource, gv)
+		}
+	}
+	return nil
+}
+
+// nestedInt64Path reads an int64 at <path>, descending into lists for
+// numeric path segments. unstructured.NestedInt64 cannot index lists.
+func nestedInt64Path(obj interface{}, path []string) (int64, bool) {
+	cur := obj
+	for _, segment := range path {
+		switch typed := cur.(type) {
+		case map[string]interface{}:
+			next, check := typed[segment]
+			if !check {
+				return 0, false
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(typed) {
+				return 0, false
+			}
+			cur = typed[idx]
+		default:
+			return 0, false
+		}
+	}
+	val, check := cur.(int64)
+	return val, check
+}
+
+// setNestedInt64Path writes an int64 at <path>, descending into lists for
+// numeric path segments.
+func setNestedInt64Path(obj interface{}, value int64, path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	cur := obj
+	for _, segment := range path[:len(path)-1] {
+		switch typed := cur.(type) {
+		case map[string]interface{}:
+			next, check := typed[segment]
+			if !check {
+				return false
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(typed) {
+				return false
+			}
+			cur = typed[idx]
+		default:
+			return false
+		}
+	}
+	last := path[len(path)-1]
+	switch typed := cur.(type) {
+	case map[string]interface{}:
+		typed[last] = value
+		return true
+	case []interface{}:
+		idx, err := strconv.Atoi(last)
+		if err != nil || idx < 0 || idx >= len(typed) {
+			return false
+		}
+		typed[idx] = value
+		return true
+	}
+	return false
+}
+
+// elasticJobRunning reports whether the job is actively running and worth
+// growing. A job whose status carries a true Succeeded, Failed, Completing
+// or Suspended condition is past the point where an extra worker helps;
+// scaling it up is pointless at best. Jobs without readable conditions keep
+// the historical assumption of running, since not every operator populates
+// status promptly.
+func elasticJobRunning(job *unstructured.Unstructured) bool {
+	conditions, found, err := unstructured.NestedSlice(job.Object, "status", "conditions")
+	if err != nil || !found {
+		return true
+	}
+	for _, conditionObj := range conditions {
+		condition, ok := conditionObj.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := condition["type"].(string)
+		conditionStatus, _ := condition["status"].(string)
+		if conditionStatus != string(metav1.ConditionTrue) {
+			continue
+		}
+		switch conditionType {
+		case "Succeeded", "Failed", "Completing", "Suspended":
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	// elasticRestConfig is the rest config the dynamic client is built
+	// from, supplied once at plugin init before any scheduling cycle runs.
+	elasticRestConfig atomic.Pointer[restclient.Config]
+	elasticClientOnce sync.Once
+	elasticClient     dynamic.Interface
+	elasticClientErr  error
+)
+
+// SetElasticJobClientConfig records the scheduler's own rest config so the
+// dynamic client shares its credentials and rate limits. Called from plugin
+// init; without it the scheduler kubeconfig on disk is read instead, which
+// keeps standalone tooling working.
+func SetElasticJobClientConfig(config *restclient.Config) {
+	elasticRestConfig.Store(config)
+}
+
+// elasticJobClient returns the shared dynamic client, built on first use.
+func elasticJobClient() (dynamic.Interface, error) {
+	elasticClientOnce.Do(func() {
+		config := elasticRestConfig.Load()
+		if config == nil {
+			config, elasticClientErr = clientcmd.BuildConfigFromFlags("", "/etc/kubernetes/scheduler.conf")
+			if elasticClientErr != nil {
+				elasticClientErr = fmt.Errorf("failed to get in-cluster config: %v", elasticClientErr)
+				return
+			}
+		}
+		elasticClient, elasticClientErr = dynamic.NewForConfig(config)
+	})
+	return elasticClient, elasticClientErr
+}
+
+// GetElasticJob fetches the job behind <adapter>.
+func (ev *Evaluator) GetElasticJob(ctx context.Context, adapter ElasticJobAdapter, ns string, jobName string) (*unstructured.Unstructured, error) {
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		klog.Infof("Failed to create dynamic client: %v", err)
+		return nil, err
+	}
+	job, err := dynamicClient.Resource(adapter.GVR).Namespace(ns).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		markElasticDegradedOnNotFound(err)
+	}
+	return job, err
+}
+
+// ElasticJobScaling adjusts the job's worker replicas by <scaleNum> and
+// records the scale-out and audit annotations, mirroring what MPIJobScaling
+// has always done but against any adapter. The update is preconditioned on
+// the read resourceVersion; on conflict the fresh object is rechecked for
+// the decision's ledger key before the mutation is reapplied, so a retried
+// decision — or a second scheduler executing the same plan during HA
+// failover — never double-increments replicas.
+func (ev *Evaluator) ElasticJobScaling(ctx context.Context, adapter ElasticJobAdapter, ns string, jobName string, scaleNum int64, auditAnnotations map[string]string) {
+	if err := ev.elasticJobScaling(ctx, adapter, ns, jobName, scaleNum, auditAnnotations); err != nil {
+		klog.Infof("Failed to update %v: %v", adapter.Name, err)
+	}
+}
+
+// elasticJobScaling is the error-returning core of ElasticJobScaling, so
+// the async executor can requeue transient failures with backoff. Permanent
+// refusals — policy denials, worker bounds, already-applied decisions —
+// return nil; retrying them would change nothing.
+func (ev *Evaluator) elasticJobScaling(ctx context.Context, adapter ElasticJobAdapter, ns string, jobName string, scaleNum int64, auditAnnotations map[string]string) error {
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		return err
+	}
+	if !allowMutation(ns + "/" + jobName) {
+		return fmt.Errorf("mutation rate limit exceeded for %v/%v", ns, jobName)
+	}
+	// One ledger key per decision and reason; batch plans touch each job at
+	// most once per reason, so the pair is unique.
+	idempotencyKey := ""
+	if planUID, check := auditAnnotations["audit.scheduling.k8s.io/plan-uid"]; check {
+		idempotencyKey = planUID + "/" + auditAnnotations["audit.scheduling.k8s.io/reason"]
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		job, err := dynamicClient.Resource(adapter.GVR).Namespace(ns).Get(ctx, jobName, metav1.GetOptions{})
+		if err != nil {
+			klog.Infof("Failed to get %v %v: %v", adapter.Name, jobName, err)
+			markElasticDegradedOnNotFound(err)
+			// A deleted job is a permanent refusal; anything else — timeouts,
+			// throttling — must reach the caller so the executor requeues the
+			// intent instead of dropping it as applied.
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		// Workloads opted out via the scale policy are never mutated.
+		if !dynamicArgs().ScalePolicy.Permits(ns, job.GetLabels()) {
+			klog.Infof("Scaling %v %v denied by scale policy", adapter.Name, jobName)
+			return nil
+		}
+
+		annotations, found, err := unstructured.NestedStringMap(job.Object, adapter.annotationsPath()...)
+		if err != nil {
+			klog.Infof("Error loading annotations: %v", err)
+			return nil
+		}
+		if !found {
+			annotations = make(map[string]string)
+		}
+		if ledgerHasKey(annotations[util.ScaleOutAnnotation], idempotencyKey) {
+			klog.Infof("Scaling %v %v already applied for decision %v", adapter.Name, jobName, idempotencyKey)
+			return nil
+		}
+
+		nowGPUcount, found := nestedInt64Path(job.Object, adapter.ReplicasPath)
+		if !found {
+			klog.Infof("Replicas not found")
+		}
+
+		// The job's declared bounds are a hard guard at the mutation point, so a
+		// stale plan or sweeper cannot push replicas outside them.
+		minWorkers, maxWorkers := adapter.workerBounds(job)
+		if target := nowGPUcount + scaleNum; target < minWorkers || target > maxWorkers {
+			klog.Infof("Scaling %v %v to %d denied: outside worker bounds [%d, %d]", adapter.Name, jobName, target, minWorkers, maxWorkers)
+			return nil
+		}
+
+		if !setNestedInt64Path(job.Object, nowGPUcount+scaleNum, adapter.ReplicasPath) {
+			klog.Infof("Failed to set replicas")
+		}
+
+		if scaleNum != 0 {
+			reason := "scale-out"
+			if scaleNum < 0 {
+				reason = "scale-in"
+			}
+			annotations[util.ScaleOutAnnotation] = appendScaleEvent(annotations[util.ScaleOutAnnotation], scaleNum, reason, idempotencyKey)
+		}
+		for key, val := range auditAnnotations {
+			annotations[key] = val
+		}
+		if err := unstructured.SetNestedStringMap(job.Object, annotations, adapter.annotationsPath()...); err != nil {
+			klog.Infof("Failed to set annotations: %v", err)
+		}
+		updatedJob, err := dynamicClient.Resource(adapter.GVR).Namespace(ns).Update(ctx, job, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		klog.Infof("Updated Info : %v", updatedJob.Object)
+		return nil
+	})
+}
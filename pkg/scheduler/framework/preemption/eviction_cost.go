@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+)
+
+// EvictionCostWeights configures the linear victim cost model shared by the
+// preemption node scoring, the retrieve ordering, and the scale-in
+// preference. Higher cost means the victim is more expensive to lose.
+type EvictionCostWeights struct {
+	// Runtime is the cost per second the victim has already been running
+	// (work lost on eviction).
+	Runtime float64 `json:"runtime,omitempty"`
+	// Priority is the cost per point of pod priority.
+	Priority float64 `json:"priority,omitempty"`
+	// Restartability is a flat penalty for pods without a controller that
+	// would recreate them.
+	Restartability float64 `json:"restartability,omitempty"`
+	// Checkpoint is the discount for victims that acknowledged a checkpoint
+	// (pre-retract-ack), whose state survives eviction.
+	Checkpoint float64 `json:"checkpoint,omitempty"`
+}
+
+// DefaultEvictionCostWeights is used until plugin args override it.
+var DefaultEvictionCostWeights = EvictionCostWeights{
+	Runtime:        1.0,
+	Priority:       100.0,
+	Restartability: 3600.0,
+	Checkpoint:     1800.0,
+}
+
+// evictionCostWeights holds the active weights.
+var evictionCostWeights = DefaultEvictionCostWeights
+
+// PodEvictionCost returns the cost of evicting <pod> under the linear model.
+func PodEvictionCost(pod *v1.Pod, w EvictionCostWeights) float64 {
+	cost := 0.0
+	if pod.Status.StartTime != nil {
+		cost += w.Runtime * time.Since(pod.Status.StartTime.Time).Seconds()
+	}
+	cost += w.Priority * float64(corev1helpers.PodPriority(pod))
+	if metav1.GetControllerOf(pod) == nil {
+		cost += w.Restartability
+	}
+	if _, check := pod.Annotations["pre-retract-ack"]; check {
+		cost -= w.Checkpoint
+	}
+	return cost
+}
+
+// sumEvictionCost returns the total cost of evicting all <pods>.
+func sumEvictionCost(pods []*v1.Pod, w EvictionCostWeights) float64 {
+	cost := 0.0
+	for _, pod := range pods {
+		cost += PodEvictionCost(pod, w)
+	}
+	return cost
+}
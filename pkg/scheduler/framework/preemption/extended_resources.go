@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// The idle-resource and retraction machinery works on whatever extended
+// resources are declared in GPUResourceNames, not only GPUs: FPGAs,
+// SmartNICs and custom accelerators are accounted the same way. Historic
+// naming keeps "GPU" in identifiers; "accelerator" helpers below sum across
+// every configured resource.
+
+// PodAcceleratorRequest exposes the pod's summed accelerator request to
+// plugins outside this package, so GPU-aware scoring and the Dynamic path
+// agree on what counts as a GPU.
+func PodAcceleratorRequest(pod *v1.Pod) int64 {
+	return podAcceleratorRequest(pod)
+}
+
+// NodeAcceleratorCapacity exposes the node's summed accelerator capacity
+// the same way, so requeue hints can tell GPU nodes from the rest.
+func NodeAcceleratorCapacity(node *v1.Node) int64 {
+	return nodeAcceleratorCapacity(node)
+}
+
+// podAcceleratorRequest computes the pod's effective request over all
+// configured extended resources, the way the scheduler's resource model
+// does: regular containers and sidecars sum, regular init containers only
+// peak before the main containers start, so the pod needs the larger of
+// the init peak and the running sum.
+func podAcceleratorRequest(pod *v1.Pod) int64 {
+	// TODO: once this tree carries the PodLevelResources API (pod-level
+	// spec.resources), prefer it over the per-container aggregation below.
+	var total int64
+	for i := range pod.Spec.Containers {
+		total += containerAcceleratorRequest(&pod.Spec.Containers[i])
+	}
+	var initPeak int64
+	for i := range pod.Spec.InitContainers {
+		container := &pod.Spec.InitContainers[i]
+		request := containerAcceleratorRequest(container)
+		// A restartable init container (sidecar) holds its accelerators
+		// for the pod's whole life.
+		if container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways {
+			total += request
+			continue
+		}
+		if request > initPeak {
+			initPeak = request
+		}
+	}
+	if initPeak > total {
+		return initPeak
+	}
+	return total
+}
+
+// containerAcceleratorRequest sums one container's requests over all
+// configured extended resources. A container that only declares limits is
+// counted by them: GPU manifests commonly set limits alone, and skipping
+// such containers would let their GPUs show up as idle.
+func containerAcceleratorRequest(container *v1.Container) int64 {
+	var total int64
+	for _, name := range dynamicArgs().GPUResourceNames {
+		if request, ok := container.Resources.Requests[v1.ResourceName(name)]; ok {
+			total += request.Value()
+			continue
+		}
+		if limit, ok := container.Resources.Limits[v1.ResourceName(name)]; ok {
+			total += limit.Value()
+		}
+	}
+	return total
+}
+
+// nodeAcceleratorCapacity sums the node's capacity over all configured
+// extended resources.
+func nodeAcceleratorCapacity(node *v1.Node) int64 {
+	var total int64
+	for _, name := range dynamicArgs().GPUResourceNames {
+		if capacity, ok := node.Status.Capacity[v1.ResourceName(name)]; ok {
+			total += capacity.Value()
+		}
+	}
+	return total
+}
+
+// The policies for models absent from the throughput table, which used to
+// index into a nil slice.
+const (
+	// UnknownModelSkip treats the workload as non-scalable.
+	UnknownModelSkip = "skip"
+	// UnknownModelLinear assumes each worker adds one unit of throughput.
+	UnknownModelLinear = "linear"
+	// UnknownModelUnschedulable reports the pending pod unschedulable with a
+	// clear message instead of guessing.
+	UnknownModelUnschedulable = "unschedulable"
+)
+
+// profileForModel resolves the throughput profile for a model. A profile
+// keyed "<primary resource>/<model>" takes precedence, so a model source can
+// carry per-resource profiles (an FPGA pipeline rarely scales like the same
+// model on GPUs); the bare model name remains the fallback. An unknown model
+// yields a synthetic linear profile under the "linear" policy and false
+// otherwise, leaving the caller to apply its policy.
+func profileForModel(scalableModelData map[string][]float64, modelName string) ([]float64, bool) {
+	if profile, check := scalableModelData[dynamicArgs().GPUResourceNames[0]+"/"+modelName]; check {
+		return profile, true
+	}
+	if profile, check := scalableModelData[modelName]; check {
+		return profile, true
+	}
+	if dynamicArgs().UnknownModelPolicy == UnknownModelLinear {
+		return linearModelProfile(), true
+	}
+	return nil, false
+}
+
+// batchSizeAnnotation carries the workload's global batch size, the third
+// dimension of the throughput model: the same model scales very differently
+// at batch 256 and batch 4096.
+const batchSizeAnnotation = "global-batch-size"
+
+// profileForModelBatch resolves the profile for (model, batch size).
+// Batch-specific profiles are keyed "<model>@bs<batch>", with the same
+// resource-qualified precedence as plain model keys. An absent or unlisted
+// batch size reduces to the batch-agnostic profile, so tables without the
+// third dimension keep working unchanged.
+func profileForModelBatch(scalableModelData map[string][]float64, modelName string, batchSize string) ([]float64, bool) {
+	if batchSize != "" {
+		batchKey := modelName + "@bs" + batchSize
+		if profile, check := scalableModelData[dynamicArgs().GPUResourceNames[0]+"/"+batchKey]; check {
+			return profile, true
+		}
+		if profile, check := scalableModelData[batchKey]; check {
+			return profile, true
+		}
+	}
+	return profileForModel(scalableModelData, modelName)
+}
+
+// linearModelProfile covers every reachable worker count with one throughput
+// unit per worker.
+func linearModelProfile() []float64 {
+	profile := make([]float64, dynamicArgs().MaxWorkerReplicas+2)
+	for i := range profile {
+		profile[i] = float64(i)
+	}
+	return profile
+}
+
+// At most one unknown-model note per model per hour.
+var (
+	unknownModelLock  sync.Mutex
+	unknownModelNoted = make(map[string]time.Time)
+)
+
+// noteMissingModelAnnotation surfaces a GPU pod carrying no model-name
+// annotation at all, once per pod per hour. Such pods are scored as
+// fixed-size candidates at their default eviction cost.
+func (ev *Evaluator) noteMissingModelAnnotation(pod *v1.Pod) {
+	key := "pod/" + pod.Namespace + "/" + pod.Name
+	unknownModelLock.Lock()
+	if time.Since(unknownModelNoted[key]) < time.Hour {
+		unknownModelLock.Unlock()
+		return
+	}
+	unknownModelNoted[key] = time.Now()
+	unknownModelLock.Unlock()
+	klog.Infof("Pod %v has no model-name annotation; treated as fixed-size", pod.Name)
+	if ev.Handler != nil {
+		ev.Handler.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "MissingModelName", "ElasticPlanning", "pod has no model-name annotation; treated as a fixed-size workload")
+	}
+}
+
+// noteUnknownModel surfaces a model missing from the throughput table via
+// log and a warning event, so the gap is visible without a crash to debug.
+func (ev *Evaluator) noteUnknownModel(pod *v1.Pod, modelName string) {
+	unknownModelLock.Lock()
+	if time.Since(unknownModelNoted[modelName]) < time.Hour {
+		unknownModelLock.Unlock()
+		return
+	}
+	unknownModelNoted[modelName] = time.Now()
+	unknownModelLock.Unlock()
+	klog.Infof("Model %q is absent from the throughput table; applying policy %q", modelName, dynamicArgs().UnknownModelPolicy)
+	if ev.Handler != nil {
+		ev.Handler.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "UnknownModel", "ElasticPlanning", "model %q is absent from the throughput table; applying policy %q", modelName, dynamicArgs().UnknownModelPolicy)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// gpuHourBudgetAnnotation declares how many GPU-hours a job may consume
+// before scheduler-driven scale-out is rolled back and paused.
+const gpuHourBudgetAnnotation = "gpu-hour-budget"
+
+// budgetSweepInterval is how often exhausted jobs are scaled back to baseline.
+const budgetSweepInterval = 5 * time.Minute
+
+// mpiJobGPUHoursUsed estimates the job's GPU-hour consumption as worker
+// replicas times elapsed wall time since creation.
+func mpiJobGPUHoursUsed(MPIJob *unstructured.Unstructured) float64 {
+	replicas, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+	if err != nil || !found {
+		return 0
+	}
+	created := MPIJob.GetCreationTimestamp()
+	if created.IsZero() {
+		return 0
+	}
+	return float64(replicas) * time.Since(created.Time).Hours()
+}
+
+// mpiJobBudgetExhausted reports whether the job declared a GPU-hour budget
+// and has consumed it.
+func mpiJobBudgetExhausted(MPIJob *unstructured.Unstructured) bool {
+	annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
+	if err != nil || !found {
+		return false
+	}
+	budgetStr, check := annotations[gpuHourBudgetAnnotation]
+	if !check {
+		return false
+	}
+	budget, convErr := strconv.ParseFloat(budgetStr, 64)
+	if convErr != nil || budget <= 0 {
+		return false
+	}
+	return mpiJobGPUHoursUsed(MPIJob) >= budget
+}
+
+// RunBudgetSweep periodically scales jobs whose GPU-hour budget is exhausted
+// back to their baseline replica count and emits an event, until ctx is done.
+func (ev *Evaluator) RunBudgetSweep(ctx context.Context) {
+	wait.UntilWithContext(ctx, ev.budgetSweep, budgetSweepInterval)
+}
+
+func (ev *Evaluator) budgetSweep(ctx context.Context) {
+	nodes, err := ev.Handler.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Node info error in budgetSweep")
+		return
+	}
+	for _, MPIJobName := range ev.ListRunningMPIJob(ctx, nodes) {
+		MPIJob, err := ev.GetMPIJob(ctx, dynamicArgs().Namespace, MPIJobName)
+		if err != nil {
+			klog.Infof("Failed to get MPIJob: %v", err)
+			continue
+		}
+		if !mpiJobBudgetExhausted(MPIJob) {
+			continue
+		}
+		annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
+		if err != nil || !found {
+			continue
+		}
+		scaleOut, convErr := strconv.Atoi(annotations[util.ScaleOutAnnotation])
+		if convErr != nil || scaleOut <= 0 {
+			continue
+		}
+		klog.Infof("GPU-hour budget exhausted, scaling %v back to baseline", MPIJobName)
+		ev.MPIJobScaling(ctx, dynamicArgs().Namespace, MPIJobName, -int64(scaleOut), map[string]string{
+			"audit.scheduling.k8s.io/reason": "gpu-hour-budget-exhausted",
+		})
+		ev.Handler.EventRecorder().Eventf(MPIJob, nil, v1.EventTypeWarning, "GPUBudgetExhausted", "ScaleIn", "GPU-hour budget exhausted, scaled back by %d workers", scaleOut)
+	}
+}
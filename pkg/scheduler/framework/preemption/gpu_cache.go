@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// The GPU allocation cache keeps one ledger per node — capacity, allocated,
+// nominated and pending-retraction accelerators — maintained from pod and
+// node informer events. Planning paths read it instead of walking every
+// node's pods each cycle; the walks remain as fallback until the informers
+// have synced. Pods carrying their GPUs as ResourceClaims are only seen by
+// the fallback accounting.
+
+// nodeGPUAllocation is one node's ledger.
+type nodeGPUAllocation struct {
+	capacity  int64
+	allocated int64
+	healthy   bool
+}
+
+// gpuReservation marks accelerators spoken for but not yet visible in pod
+// state: a nomination on a freed node, or a victim whose deletion is in
+// flight.
+type gpuReservation struct {
+	node string
+	gpus int64
+}
+
+var (
+	gpuCacheLock  sync.RWMutex
+	gpuCacheNodes map[string]*nodeGPUAllocation
+	// Keyed by preemptor pod; cleared when the preemptor binds or goes away.
+	nominatedGPUs = map[string]gpuReservation{}
+	// Keyed by victim pod; cleared when the victim's deletion is observed.
+	retractionGPUs = map[string]gpuReservation{}
+
+	gpuCachePodsSynced  cache.InformerSynced
+	gpuCacheNodesSynced cache.InformerSynced
+)
+
+// InitGPUAllocationCache subscribes the cache to the shared pod and node
+// informers. It is idempotent so multiple scheduler profiles can call it.
+func InitGPUAllocationCache(podInformer cache.SharedIndexInformer, nodeInformer cache.SharedIndexInformer) error {
+	gpuCacheLock.Lock()
+	defer gpuCacheLock.Unlock()
+	if gpuCacheNodes != nil {
+		return nil
+	}
+	gpuCacheNodes = map[string]*nodeGPUAllocation{}
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { cacheApplyPod(nil, podFromCacheObj(obj)) },
+		UpdateFunc: func(oldObj, newObj interface{}) { cacheApplyPod(podFromCacheObj(oldObj), podFromCacheObj(newObj)) },
+		DeleteFunc: func(obj interface{}) { cacheApplyPod(podFromCacheObj(obj), nil) },
+	}); err != nil {
+		return err
+	}
+	if _, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { cacheApplyNode(nodeFromCacheObj(obj)) },
+		UpdateFunc: func(oldObj, newObj interface{}) { cacheApplyNode(nodeFromCacheObj(newObj)) },
+		DeleteFunc: func(obj interface{}) { cacheRemoveNode(nodeFromCacheObj(obj)) },
+	}); err != nil {
+		return err
+	}
+	gpuCachePodsSynced = podInformer.HasSynced
+	gpuCacheNodesSynced = nodeInformer.HasSynced
+	return nil
+}
+
+// podFromCacheObj unwraps informer objects, including deletion tombstones.
+func podFromCacheObj(obj interface{}) *v1.Pod {
+	if pod, ok := obj.(*v1.Pod); ok {
+		return pod
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		if pod, ok := tombstone.Obj.(*v1.Pod); ok {
+			return pod
+		}
+	}
+	return nil
+}
+
+func nodeFromCacheObj(obj interface{}) *v1.Node {
+	if node, ok := obj.(*v1.Node); ok {
+		return node
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		if node, ok := tombstone.Obj.(*v1.Node); ok {
+			return node
+		}
+	}
+	return nil
+}
+
+// cachePodGPUs is the pod's contribution to its node's allocated count:
+// zero for unbound and terminal pods.
+func cachePodGPUs(pod *v1.Pod) (node string, gpus int64) {
+	if pod == nil || pod.Spec.NodeName == "" {
+		return "", 0
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return "", 0
+	}
+	return pod.Spec.NodeName, podAcceleratorRequest(pod)
+}
+
+// cacheApplyPod replaces oldPod's contribution with newPod's and retires
+// reservations the transition settles: a preemptor that bound consumes its
+// nomination, a victim that vanished completes its retraction.
+func cacheApplyPod(oldPod, newPod *v1.Pod) {
+	// Fair-queueing usage moves with the pod between tenants.
+	if _, gpus := cachePodGPUs(oldPod); gpus > 0 {
+		util.AdjustTenantGPUUsage(util.TenantOfPod(oldPod), -gpus)
+	}
+	if _, gpus := cachePodGPUs(newPod); gpus > 0 {
+		util.AdjustTenantGPUUsage(util.TenantOfPod(newPod), gpus)
+	}
+	gpuCacheLock.Lock()
+	defer gpuCacheLock.Unlock()
+	if oldNode, oldGPUs := cachePodGPUs(oldPod); oldGPUs > 0 {
+		if ledger, check := gpuCacheNodes[oldNode]; check {
+			ledger.allocated -= oldGPUs
+		}
+	}
+	if newNode, newGPUs := cachePodGPUs(newPod); newGPUs > 0 {
+		ledger, check := gpuCacheNodes[newNode]
+		if !check {
+			ledger = &nodeGPUAllocation{healthy: true}
+			gpuCacheNodes[newNode] = ledger
+		}
+		ledger.allocated += newGPUs
+		delete(nominatedGPUs, newPod.Namespace+"/"+newPod.Name)
+	}
+	if newPod == nil && oldPod != nil {
+		delete(nominatedGPUs, oldPod.Namespace+"/"+oldPod.Name)
+		delete(retractionGPUs, oldPod.Namespace+"/"+oldPod.Name)
+	}
+}
+
+func cacheApplyNode(node *v1.Node) {
+	if node == nil {
+		return
+	}
+	gpuCacheLock.Lock()
+	defer gpuCacheLock.Unlock()
+	ledger, check := gpuCacheNodes[node.Name]
+	if !check {
+		ledger = &nodeGPUAllocation{}
+		gpuCacheNodes[node.Name] = ledger
+	}
+	ledger.capacity = nodeAcceleratorCapacity(node)
+	ledger.healthy = nodeGPUsHealthy(node)
+}
+
+func cacheRemoveNode(node *v1.Node) {
+	if node == nil {
+		return
+	}
+	gpuCacheLock.Lock()
+	delete(gpuCacheNodes, node.Name)
+	gpuCacheLock.Unlock()
+}
+
+// RecordNominatedGPUs reserves accelerators on a freed node for a pending
+// preemptor, so the cache does not report them idle while the preemptor is
+// still making its way to a bind.
+func RecordNominatedGPUs(preemptor *v1.Pod, nodeName string) {
+	gpus := podAcceleratorRequest(preemptor)
+	if gpus == 0 {
+		return
+	}
+	gpuCacheLock.Lock()
+	nominatedGPUs[preemptor.Namespace+"/"+preemptor.Name] = gpuReservation{node: nodeName, gpus: gpus}
+	gpuCacheLock.Unlock()
+}
+
+// RecordPendingRetraction marks a victim whose deletion was issued but not
+// yet observed; its accelerators are allocated until the delete event lands
+// but already spoken for.
+func RecordPendingRetraction(victim *v1.Pod) {
+	node, gpus := cachePodGPUs(victim)
+	if gpus == 0 {
+		return
+	}
+	gpuCacheLock.Lock()
+	retractionGPUs[victim.Namespace+"/"+victim.Name] = gpuReservation{node: node, gpus: gpus}
+	gpuCacheLock.Unlock()
+}
+
+// gpuCacheReady reports whether both informers have delivered their initial
+// state; before that the cache undercounts and must not be read.
+func gpuCacheReady() bool {
+	return gpuCachePodsSynced != nil && gpuCachePodsSynced() && gpuCacheNodesSynced != nil && gpuCacheNodesSynced()
+}
+
+// NodeIdleGPUs returns the node's idle accelerators from the cache:
+// capacity minus allocated minus nominated, on healthy nodes. ok is false
+// when the cache cannot answer yet.
+func NodeIdleGPUs(nodeName string) (idle int64, ok bool) {
+	if !gpuCacheReady() {
+		return 0, false
+	}
+	gpuCacheLock.RLock()
+	defer gpuCacheLock.RUnlock()
+	ledger, check := gpuCacheNodes[nodeName]
+	if !check || !ledger.healthy {
+		return 0, check
+	}
+	idle = ledger.capacity - ledger.allocated
+	for _, reservation := range nominatedGPUs {
+		if reservation.node == nodeName {
+			idle -= reservation.gpus
+		}
+	}
+	if idle < 0 {
+		idle = 0
+	}
+	return idle, true
+}
+
+// clusterIdleGPUsFromCache sums idle accelerators across healthy nodes.
+func clusterIdleGPUsFromCache() (int64, bool) {
+	if !gpuCacheReady() {
+		return 0, false
+	}
+	gpuCacheLock.RLock()
+	defer gpuCacheLock.RUnlock()
+	var total int64
+	for _, ledger := range gpuCacheNodes {
+		if !ledger.healthy {
+			continue
+		}
+		total += ledger.capacity - ledger.allocated
+	}
+	for _, reservation := range nominatedGPUs {
+		total -= reservation.gpus
+	}
+	if total < 0 {
+		total = 0
+	}
+	return total, true
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// GPU health signals. Clusters running the DCGM exporter typically feed its
+// device health into node-problem-detector, which surfaces it as a node
+// condition; simpler setups label the node directly. Either marks every GPU
+// on the node unusable for planning: its idle GPUs are not counted and its
+// workloads are not retracted, since "freeing" broken GPUs helps nobody.
+const (
+	// NodeGPUUnhealthyLabel marks a node with failed GPUs, value "true".
+	NodeGPUUnhealthyLabel = "scheduler.alpha/gpu-unhealthy"
+	// NodeGPUUnhealthyCondition is the node condition type set by health
+	// monitors when device checks fail.
+	NodeGPUUnhealthyCondition v1.NodeConditionType = "GPUUnhealthy"
+)
+
+// nodeGPUsHealthy reports whether the node's GPUs are usable for planning.
+func nodeGPUsHealthy(node *v1.Node) bool {
+	if node.Labels[NodeGPUUnhealthyLabel] == "true" {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == NodeGPUUnhealthyCondition && condition.Status == v1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
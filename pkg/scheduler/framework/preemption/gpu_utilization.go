@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Real-time GPU utilization from a Prometheus-compatible endpoint scraping
+// the DCGM exporter, configured via UtilizationMetricsURL. The signal is
+// optional and advisory: planning works without it, and a slow or absent
+// endpoint degrades to the throughput-table-only behavior.
+const (
+	// utilizationQueryTimeout bounds one metrics query; planning must not
+	// hang on a slow Prometheus.
+	utilizationQueryTimeout = 3 * time.Second
+	// utilizationCacheTTL is how long a queried value is reused, so a busy
+	// scheduling loop cannot hammer the metrics endpoint.
+	utilizationCacheTTL = 30 * time.Second
+)
+
+type utilizationSample struct {
+	value float64
+	check bool
+	at    time.Time
+}
+
+var (
+	utilizationCacheLock sync.Mutex
+	utilizationCache     = make(map[string]utilizationSample)
+)
+
+// prometheusResponse is the subset of the instant-query answer we read.
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Result []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// queryUtilization runs one instant query and returns its single value in
+// percent, through the cache. Missing config, errors and empty results all
+// return false.
+func queryUtilization(ctx context.Context, query string) (float64, bool) {
+	if dynamicArgs().UtilizationMetricsURL == "" {
+		return 0, false
+	}
+	utilizationCacheLock.Lock()
+	if sample, check := utilizationCache[query]; check && time.Since(sample.at) < utilizationCacheTTL {
+		utilizationCacheLock.Unlock()
+		return sample.value, sample.check
+	}
+	utilizationCacheLock.Unlock()
+
+	value, check := queryUtilizationUncached(ctx, query)
+	utilizationCacheLock.Lock()
+	utilizationCache[query] = utilizationSample{value: value, check: check, at: time.Now()}
+	utilizationCacheLock.Unlock()
+	return value, check
+}
+
+func queryUtilizationUncached(ctx context.Context, query string) (float64, bool) {
+	queryCtx, cancel := context.WithTimeout(ctx, utilizationQueryTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(queryCtx, http.MethodGet, dynamicArgs().UtilizationMetricsURL+"/api/v1/query?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		klog.Infof("Failed to build utilization query: %v", err)
+		return 0, false
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		klog.Infof("Utilization query failed: %v", err)
+		return 0, false
+	}
+	defer response.Body.Close()
+	var decoded prometheusResponse
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil {
+		klog.Infof("Unreadable utilization response: %v", err)
+		return 0, false
+	}
+	if decoded.Status != "success" || len(decoded.Data.Result) == 0 || len(decoded.Data.Result[0].Value) < 2 {
+		return 0, false
+	}
+	raw, ok := decoded.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// jobGPUUtilization returns the average GPU utilization across the job's
+// pods, in percent.
+func (ev *Evaluator) jobGPUUtilization(ctx context.Context, jobName string) (float64, bool) {
+	return queryUtilization(ctx, fmt.Sprintf("avg(DCGM_FI_DEV_GPU_UTIL{pod=~%q})", jobName+"-.*"))
+}
+
+// podGPUUtilization returns the pod's average GPU utilization in percent.
+func (ev *Evaluator) podGPUUtilization(ctx context.Context, pod *v1.Pod) (float64, bool) {
+	return queryUtilization(ctx, fmt.Sprintf("avg(DCGM_FI_DEV_GPU_UTIL{pod=%q})", pod.Name))
+}
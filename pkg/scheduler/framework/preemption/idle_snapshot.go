@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// IdleGPUsFromSnapshot computes cluster-wide idle GPUs from the scheduling
+// cycle's node snapshot, whose NodeInfos already track requested resources,
+// instead of listing nodes and pods through the API server. It is exported
+// for reuse by filter and score plugins that need the same number the
+// Dynamic path acts on. Callers outside a scheduling cycle (sweepers,
+// simulation) get an error and should fall back to API accounting.
+func (ev *Evaluator) IdleGPUsFromSnapshot() (int64, error) {
+	if ev.Handler == nil {
+		return 0, fmt.Errorf("no framework handle")
+	}
+	nodeInfos, err := ev.Handler.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		// Unhealthy GPUs are not idle capacity.
+		if !nodeGPUsHealthy(node) {
+			continue
+		}
+		var capacity int64
+		for _, name := range dynamicArgs().GPUResourceNames {
+			capacity += nodeInfo.Allocatable.ScalarResources[v1.ResourceName(name)]
+		}
+		if capacity == 0 {
+			continue
+		}
+		idle := NodeInfoIdleAccelerators(nodeInfo)
+		recordIdleGPUs(node.Name, idle)
+		metrics.IdleGPUsPerNode.WithLabelValues(node.Name).Set(float64(idle))
+		total += idle
+	}
+	metrics.IdleGPUsTotal.Set(float64(total))
+	return total, nil
+}
+
+// NodeInfoIdleAccelerators returns the node's allocatable minus requested
+// accelerators from its NodeInfo, over all configured resources. Shared
+// with score plugins that need the same number the Dynamic path acts on.
+func NodeInfoIdleAccelerators(nodeInfo *framework.NodeInfo) int64 {
+	var idle int64
+	for _, name := range dynamicArgs().GPUResourceNames {
+		idle += nodeInfo.Allocatable.ScalarResources[v1.ResourceName(name)] - nodeInfo.Requested.ScalarResources[v1.ResourceName(name)]
+	}
+	return idle
+}
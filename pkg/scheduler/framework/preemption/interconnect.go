@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// GPU interconnect classes derived from Node Feature Discovery labels.
+const (
+	interconnectNVLink = "nvlink"
+	interconnectIB     = "ib"
+	interconnectPCIe   = "pcie"
+)
+
+// interconnectClassLabels maps NFD-provided node labels to an interconnect
+// class, in preference order (fastest first).
+var interconnectClassLabels = []struct {
+	label string
+	class string
+}{
+	{"feature.node.kubernetes.io/nvlink", interconnectNVLink},
+	{"feature.node.kubernetes.io/rdma.available", interconnectIB},
+	{"feature.node.kubernetes.io/pci-gen4", interconnectPCIe},
+}
+
+// defaultScalableModelData holds throughput per worker count measured on the
+// default (PCIe) interconnect.
+var defaultScalableModelData = map[string][]float64{
+	"VGG16":       {4.232, 3.499, 4.61, 4.526, 5.396, 6.363},
+	"VGG19":       {3.693, 2.793, 3.6294, 4.3105, 5.2051, 5.934},
+	"inceptionv3": {3.5, 5.495, 6.29, 8.449, 9.693, 11.262},
+	"alex-net":    {15.969, 7.496, 9.002, 10.572, 13.005, 14.708},
+	"resnet50":    {6.78098, 7.777, 12.4701, 12.18717, 15.90254, 18.968},
+	"resnet101":   {4.253, 5.519, 8.863, 8.8171, 9.686, 12.904},
+}
+
+// interconnectThroughputScale refines the default profile per interconnect
+// class. All-reduce heavy models scale better over faster links.
+var interconnectThroughputScale = map[string]float64{
+	interconnectNVLink: 1.25,
+	interconnectIB:     1.1,
+	interconnectPCIe:   1.0,
+}
+
+// nodeInterconnectClass returns the interconnect class of a node based on its
+// NFD labels, defaulting to PCIe when no label matches.
+func nodeInterconnectClass(node *v1.Node) string {
+	for _, entry := range interconnectClassLabels {
+		if val, check := node.Labels[entry.label]; check && val == "true" {
+			return entry.class
+		}
+	}
+	return interconnectPCIe
+}
+
+// clusterInterconnectClass returns the best interconnect class among GPU
+// nodes, which is where scale-out workers are preferred to land.
+func clusterInterconnectClass(nodes *v1.NodeList) string {
+	best := interconnectPCIe
+	for _, node := range nodes.Items {
+		if _, check := node.Status.Capacity[gpuResource()]; !check {
+			continue
+		}
+		class := nodeInterconnectClass(&node)
+		if interconnectThroughputScale[class] > interconnectThroughputScale[best] {
+			best = class
+		}
+	}
+	return best
+}
+
+// throughputProfileForClass returns the throughput table adjusted for the
+// given interconnect class.
+func throughputProfileForClass(class string) map[string][]float64 {
+	scale, check := interconnectThroughputScale[class]
+	if !check {
+		scale = 1.0
+	}
+	scalableModelData := make(map[string][]float64, len(activeScalableModelData()))
+	for model, profile := range activeScalableModelData() {
+		scaled := make([]float64, len(profile))
+		for i, throughput := range profile {
+			scaled[i] = throughput * scale
+		}
+		scalableModelData[model] = scaled
+	}
+	return scalableModelData
+}
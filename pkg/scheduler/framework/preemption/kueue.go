@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+// kueueWorkloadGVR locates Kueue's namespaced Workload resource. With the
+// integration enabled, Dynamic only touches jobs Kueue has admitted, and
+// reclaims capacity by deactivating Workloads instead of deleting pods
+// behind Kueue's back — otherwise the two controllers fight over the same
+// capacity.
+var kueueWorkloadGVR = schema.GroupVersionResource{
+	Group:    "kueue.x-k8s.io",
+	Version:  "v1beta1",
+	Resource: "workloads",
+}
+
+// workloadOwnedBy returns the Workload in <namespace> whose owner references
+// name <ownerName>, or nil.
+func workloadOwnedBy(ctx context.Context, namespace, ownerName string) *unstructured.Unstructured {
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		klog.Infof("Failed to create dynamic client for Kueue lookup: %v", err)
+		return nil
+	}
+	workloads, err := dynamicClient.Resource(kueueWorkloadGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Failed to list Kueue Workloads in %v: %v", namespace, err)
+		return nil
+	}
+	for i := range workloads.Items {
+		for _, owner := range workloads.Items[i].GetOwnerReferences() {
+			if owner.Name == ownerName {
+				return &workloads.Items[i]
+			}
+		}
+	}
+	return nil
+}
+
+// workloadAdmitted reads the Workload's Admitted condition.
+func workloadAdmitted(workload *unstructured.Unstructured) bool {
+	conditions, found, err := unstructured.NestedSlice(workload.Object, "status", "conditions")
+	if err != nil || !found {
+		return false
+	}
+	for _, entry := range conditions {
+		condition, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "Admitted" && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// jobWorkloadAdmitted reports whether the job may be scaled under the Kueue
+// integration: true when the integration is off, and otherwise only when
+// the job's Workload exists and is admitted. A job Kueue has not admitted
+// is Kueue's to manage, not ours.
+func (ev *Evaluator) jobWorkloadAdmitted(ctx context.Context, namespace, jobName string) bool {
+	if !dynamicArgs().KueueIntegration || ev.Snapshot != nil {
+		return true
+	}
+	workload := workloadOwnedBy(ctx, namespace, jobName)
+	return workload != nil && workloadAdmitted(workload)
+}
+
+// evictVictimViaKueue reclaims the victim's capacity by deactivating its
+// owning Workload, so Kueue requeues the workload itself instead of
+// discovering a pod deleted under it. It reports whether the eviction was
+// handed over; false sends the caller down the raw deletion path.
+func (ev *Evaluator) evictVictimViaKueue(ctx context.Context, victim *v1.Pod) bool {
+	if !dynamicArgs().KueueIntegration || ev.Snapshot != nil {
+		return false
+	}
+	ownerName := victim.Name
+	if controller := metav1.GetControllerOf(victim); controller != nil {
+		ownerName = controller.Name
+	}
+	workload := workloadOwnedBy(ctx, victim.Namespace, ownerName)
+	if workload == nil {
+		return false
+	}
+	if err := unstructured.SetNestedField(workload.Object, false, "spec", "active"); err != nil {
+		klog.Infof("Failed to deactivate Workload %v: %v", workload.GetName(), err)
+		return false
+	}
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		return false
+	}
+	if _, err := dynamicClient.Resource(kueueWorkloadGVR).Namespace(victim.Namespace).Update(ctx, workload, metav1.UpdateOptions{}); err != nil {
+		klog.Infof("Failed to update Workload %v: %v", workload.GetName(), err)
+		return false
+	}
+	klog.Infof("Deactivated Kueue Workload %v to reclaim %v", workload.GetName(), victim.Name)
+	return true
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// mpiJobInformerResync keeps the shared cache honest against missed watch
+// events.
+const mpiJobInformerResync = 30 * time.Second
+
+// One shared dynamic informer over the MPIJob GVR, started on first use.
+// Every planning pass used to GET each running job through the apiserver;
+// the shared cache gives all plugins one consistent view at zero API cost.
+var (
+	mpiJobInformerOnce   sync.Once
+	mpiJobGenericLister  cache.GenericLister
+	mpiJobInformerSynced cache.InformerSynced
+)
+
+// sharedMPIJobLister returns the lister of the shared MPIJob informer, or
+// nil while the informer is still syncing (or could not be built), in which
+// case callers fall back to direct API reads.
+func sharedMPIJobLister() cache.GenericLister {
+	mpiJobInformerOnce.Do(func() {
+		dynamicClient, err := elasticJobClient()
+		if err != nil {
+			klog.Infof("Failed to create dynamic client for MPIJob informer: %v", err)
+			return
+		}
+		factory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, mpiJobInformerResync)
+		informer := factory.ForResource(mpiJobAdapter.GVR)
+		mpiJobGenericLister = informer.Lister()
+		mpiJobInformerSynced = informer.Informer().HasSynced
+		factory.Start(wait.NeverStop)
+	})
+	if mpiJobGenericLister == nil || !mpiJobInformerSynced() {
+		return nil
+	}
+	return mpiJobGenericLister
+}
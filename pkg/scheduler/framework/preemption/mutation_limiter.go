@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+)
+
+// Token buckets over scale and retract API writes, one cluster-wide and one
+// per job, so a burst of unschedulable pods cannot hammer the API server
+// with mutations every scheduling cycle. Limiters are rebuilt lazily after
+// a config change.
+var (
+	mutationLimiterLock    sync.Mutex
+	clusterMutationLimiter flowcontrol.RateLimiter
+	jobMutationLimiters    map[string]flowcontrol.RateLimiter
+)
+
+// resetMutationLimiters drops the built limiters so new args take effect.
+func resetMutationLimiters() {
+	mutationLimiterLock.Lock()
+	defer mutationLimiterLock.Unlock()
+	clusterMutationLimiter = nil
+	jobMutationLimiters = nil
+}
+
+// tokenBucket builds a limiter, defaulting the burst to one token.
+func tokenBucket(qps float64, burst int64) flowcontrol.RateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(qps), int(burst))
+}
+
+// allowMutation consumes one token for a scale or retract write against
+// <jobKey> from both buckets; a zero QPS leaves the corresponding bucket
+// disabled. Denied writes are dropped, not queued: the next scheduling
+// cycle recomputes the decision anyway.
+func allowMutation(jobKey string) bool {
+	if dynamicArgs().MutationQPS == 0 && dynamicArgs().JobMutationQPS == 0 {
+		return true
+	}
+	mutationLimiterLock.Lock()
+	defer mutationLimiterLock.Unlock()
+	if dynamicArgs().MutationQPS > 0 {
+		if clusterMutationLimiter == nil {
+			clusterMutationLimiter = tokenBucket(dynamicArgs().MutationQPS, dynamicArgs().MutationBurst)
+		}
+		if !clusterMutationLimiter.TryAccept() {
+			klog.Infof("Mutation of %v suppressed by cluster-wide rate limit", jobKey)
+			return false
+		}
+	}
+	if dynamicArgs().JobMutationQPS > 0 {
+		if jobMutationLimiters == nil {
+			jobMutationLimiters = make(map[string]flowcontrol.RateLimiter)
+		}
+		limiter, check := jobMutationLimiters[jobKey]
+		if !check {
+			limiter = tokenBucket(dynamicArgs().JobMutationQPS, dynamicArgs().JobMutationBurst)
+			jobMutationLimiters[jobKey] = limiter
+		}
+		if !limiter.TryAccept() {
+			klog.Infof("Mutation of %v suppressed by per-job rate limit", jobKey)
+			return false
+		}
+	}
+	return true
+}
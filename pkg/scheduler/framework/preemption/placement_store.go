@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// PodPlacement is one GPU pod's recorded placement, written at PostBind by
+// the placement tracker plugin and read by the planner instead of walking
+// every node's pods again.
+type PodPlacement struct {
+	// Node is where the pod was bound.
+	Node string
+	// GPUs is the pod's effective accelerator request.
+	GPUs int64
+	// Backfilled is true when the pod was placed into temporarily idle GPUs.
+	Backfilled bool
+	// Job is the owning elastic job, empty for standalone pods.
+	Job string
+}
+
+// The store only knows pods bound since the scheduler started; readers fall
+// back to the pod index when a job has no recorded placements.
+var (
+	placementLock sync.RWMutex
+	podPlacements = map[string]PodPlacement{}
+)
+
+// RecordPlacement stores where a just-bound GPU pod landed. Pods without
+// accelerators are not tracked.
+func RecordPlacement(pod *v1.Pod, nodeName string) {
+	gpus := podAcceleratorRequest(pod)
+	if gpus == 0 {
+		return
+	}
+	placement := PodPlacement{
+		Node:       nodeName,
+		GPUs:       gpus,
+		Backfilled: pod.Annotations[util.SchedulingStateAnnotation] == util.SchedulingStateBackfilled,
+		Job:        elasticJobNameOfPod(pod),
+	}
+	placementLock.Lock()
+	podPlacements[pod.Namespace+"/"+pod.Name] = placement
+	placementLock.Unlock()
+}
+
+// ForgetPlacement drops a pod from the store, called when the scheduler
+// itself removes the pod (retraction) or observes its deletion.
+func ForgetPlacement(namespace, name string) {
+	placementLock.Lock()
+	delete(podPlacements, namespace+"/"+name)
+	placementLock.Unlock()
+}
+
+// jobPlacementNodes returns the nodes hosting the job's recorded pods.
+// tracked is false when the store has never seen the job, in which case the
+// caller must fall back to walking the pod index.
+func jobPlacementNodes(jobName string) (nodeNames map[string]bool, tracked bool) {
+	placementLock.RLock()
+	defer placementLock.RUnlock()
+	for _, placement := range podPlacements {
+		if placement.Job != jobName {
+			continue
+		}
+		if nodeNames == nil {
+			nodeNames = map[string]bool{}
+		}
+		nodeNames[placement.Node] = true
+	}
+	return nodeNames, nodeNames != nil
+}
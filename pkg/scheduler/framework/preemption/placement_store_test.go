@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+func TestRecordPlacement(t *testing.T) {
+	pinDynamicArgs(t)
+
+	gpuRes := map[v1.ResourceName]string{"nvidia.com/gpu": "1"}
+	worker0 := st.MakePod().Namespace("my-ns").Name("store-elastic-worker-0").Req(gpuRes).Obj()
+	worker1 := st.MakePod().Namespace("my-ns").Name("store-elastic-worker-1").Req(gpuRes).Obj()
+	cpuPod := st.MakePod().Namespace("my-ns").Name("store-elastic-worker-2").Obj()
+	t.Cleanup(func() {
+		for _, pod := range []*v1.Pod{worker0, worker1, cpuPod} {
+			ForgetPlacement(pod.Namespace, pod.Name)
+		}
+	})
+
+	RecordPlacement(worker0, "node-a")
+	RecordPlacement(worker1, "node-b")
+	// Pods without accelerators are not the planner's business.
+	RecordPlacement(cpuPod, "node-c")
+
+	nodeNames, tracked := jobPlacementNodes("store-elastic")
+	if !tracked {
+		t.Fatalf("jobPlacementNodes() tracked = false, want true after recording workers")
+	}
+	if !nodeNames["node-a"] || !nodeNames["node-b"] || len(nodeNames) != 2 {
+		t.Errorf("jobPlacementNodes() = %v, want exactly node-a and node-b", nodeNames)
+	}
+
+	ForgetPlacement(worker0.Namespace, worker0.Name)
+	ForgetPlacement(worker1.Namespace, worker1.Name)
+	if _, tracked := jobPlacementNodes("store-elastic"); tracked {
+		t.Errorf("jobPlacementNodes() tracked = true after forgetting every worker, want false")
+	}
+}
+
+// TestRecordPlacementBackfilled checks the backfill marker survives into the
+// store so retraction planning can tell backfills from regular placements.
+func TestRecordPlacementBackfilled(t *testing.T) {
+	pinDynamicArgs(t)
+
+	pod := st.MakePod().Namespace("my-ns").Name("lone-gpu-pod").
+		Req(map[v1.ResourceName]string{"nvidia.com/gpu": "2"}).
+		Annotation(util.SchedulingStateAnnotation, util.SchedulingStateBackfilled).
+		Obj()
+	t.Cleanup(func() { ForgetPlacement(pod.Namespace, pod.Name) })
+
+	RecordPlacement(pod, "node-a")
+	placementLock.RLock()
+	placement, check := podPlacements[pod.Namespace+"/"+pod.Name]
+	placementLock.RUnlock()
+	if !check {
+		t.Fatalf("RecordPlacement() did not store the pod")
+	}
+	want := PodPlacement{Node: "node-a", GPUs: 2, Backfilled: true}
+	if placement != want {
+		t.Errorf("RecordPlacement() stored %+v, want %+v", placement, want)
+	}
+}
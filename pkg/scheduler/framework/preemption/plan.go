@@ -0,0 +1,437 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	v1helper "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// preemptorCanUseNode reports whether the preemptor could land on the node
+// at all: its nodeSelector and required node affinity match, and every
+// NoSchedule/NoExecute taint is tolerated.
+func preemptorCanUseNode(pod *v1.Pod, node *v1.Node) bool {
+	match, err := nodeaffinity.GetRequiredNodeAffinity(pod).Match(node)
+	if err != nil || !match {
+		return false
+	}
+	_, untolerated := v1helper.FindMatchingUntoleratedTaint(node.Spec.Taints, pod.Spec.Tolerations, func(taint *v1.Taint) bool {
+		return taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute
+	})
+	return !untolerated
+}
+
+// RetrieveAction is one step of a retrieve plan: either the retraction of a
+// backfilled pod or the scale-in of a previously scaled-out MPIJob.
+type RetrieveAction struct {
+	// Retract is true for a pod retraction and false for a job scale-in.
+	Retract bool
+	// VictimPod is the backfilled pod to retract (retraction only).
+	VictimPod *v1.Pod
+	// MPIJobName is the job to shrink (scale-in only).
+	MPIJobName string
+	// ForceDelete is true when the victim sits on a fenced NotReady node and
+	// must be deleted with zero grace, since graceful retraction cannot
+	// complete there (retraction only).
+	ForceDelete bool
+	// GPUsFreed is the number of GPUs this action gives back.
+	GPUsFreed int
+	// ThroughputLoss is the estimated throughput lost by this action.
+	ThroughputLoss int
+}
+
+// RetrievePlan is the full set of actions the Dynamic path would execute to
+// free GPUs for a pending pod, plus its expected effect. It is computed
+// without mutating anything, so it can back dry runs, tests, debug endpoints,
+// and shadow evaluation.
+type RetrievePlan struct {
+	// Feasible is true when the plan frees at least the needed GPUs.
+	Feasible bool
+	// NeedGPUs is how many GPUs had to be freed on top of the idle ones.
+	NeedGPUs int
+	// GPUsFreed is the total number of GPUs the actions free.
+	GPUsFreed int
+	// ThroughputLoss is the summed estimated throughput loss of all actions.
+	ThroughputLoss int
+	// Actions are executed in order until enough GPUs are free.
+	Actions []RetrieveAction
+}
+
+// PlanRetrieve computes the retractions and scale-ins that Retrieve would
+// execute for <podNow> without performing any of them.
+func (ev *Evaluator) PlanRetrieve(ctx context.Context, podNow *v1.Pod, scalableModelData map[string][]float64, nodes *v1.NodeList, idleGPUs int64, requestGPUs int64, runningMPIJobs []string) *RetrievePlan {
+	return ev.planRetrieveWithWeights(ctx, podNow, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs, evictionCostWeights)
+}
+
+// planRetrieveWithWeights is PlanRetrieve under explicit eviction cost
+// weights, so a shadow policy can be evaluated next to the active one.
+func (ev *Evaluator) planRetrieveWithWeights(ctx context.Context, podNow *v1.Pod, scalableModelData map[string][]float64, nodes *v1.NodeList, idleGPUs int64, requestGPUs int64, runningMPIJobs []string, weights EvictionCostWeights) *RetrievePlan {
+	var backfilledPods []v1.Pod
+	var scaleOutMPIJobs []*unstructured.Unstructured
+	var retrieveCandidates [][]int
+	forceDeleteNodes := make(map[string]bool)
+	plan := &RetrievePlan{NeedGPUs: int(requestGPUs - idleGPUs)}
+
+	// Nodes and jobs are evaluated concurrently like DryRunPreemption
+	// evaluates candidate nodes; one slow kubelet or apiserver call no
+	// longer serializes the whole planning pass.
+	var candidatesLock sync.Mutex
+	var errs []error
+	checkNode := func(i int) {
+		node := nodes.Items[i]
+		if ctx.Err() != nil {
+			return
+		}
+		// Pods on a NotReady or Unknown node cannot be evicted gracefully
+		// and their GPUs are not quickly reclaimable, so they are no
+		// victims — unless the cluster fences dead nodes and the NotReady
+		// force-delete timeout has passed.
+		if ready, notReadySince := nodeReadyStatus(&node); !ready {
+			if !notReadyForceDeleteAllowed(notReadySince) {
+				return
+			}
+			candidatesLock.Lock()
+			forceDeleteNodes[node.Name] = true
+			candidatesLock.Unlock()
+		}
+		// Retracting victims on a node the preemptor can never land on frees
+		// nothing for it.
+		if !preemptorCanUseNode(podNow, &node) {
+			return
+		}
+		// Retracting healthy workloads to "free" broken GPUs helps nobody.
+		if !nodeGPUsHealthy(&node) {
+			return
+		}
+		pods, err := ev.podsOnNode(ctx, node.Name)
+		if err != nil {
+			candidatesLock.Lock()
+			errs = append(errs, err)
+			candidatesLock.Unlock()
+			return
+		}
+		for _, pod := range pods {
+			if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "backfilled" {
+				// Namespace-scoped mode only retracts the preemptor's own
+				// namespace's pods.
+				if !namespaceInScope(podNow, pod.Namespace) {
+					continue
+				}
+				// A protected namespace's pods are never retraction victims.
+				if ev.namespaceProtected(ctx, pod.Namespace) {
+					continue
+				}
+				// Jobs that cannot checkpoint may opt out of retraction.
+				if util.PodOptedOutOfRetraction(pod) {
+					continue
+				}
+				// A pod past its backfill lease is effectively promoted even
+				// if the sweeper has not restamped it yet.
+				if backfillLeaseExpired(pod) {
+					continue
+				}
+				podNowCreationTimeStamp := getPodTimestamp(podNow)
+				podCreationTimeStamp := getPodTimestamp(pod)
+
+				if podNowCreationTimeStamp.Before(&podCreationTimeStamp) {
+					candidatesLock.Lock()
+					backfilledPods = append(backfilledPods, *pod)
+					candidatesLock.Unlock()
+				}
+			}
+		}
+	}
+	ev.parallelUntil(ctx, len(nodes.Items), checkNode)
+	// Without the MPIJob CRD only retraction can free GPUs; scale-in would
+	// go nowhere. A retract-only profile keeps its hands off jobs entirely.
+	if ElasticJobsAvailable() && !profilePolicy(ev.profileName()).RetractOnly && dynamicArgs().RetrievePolicy != RetrievePolicyRetractOnly && namespaceInScope(podNow, dynamicArgs().Namespace) && !ev.namespaceProtected(ctx, dynamicArgs().Namespace) {
+		checkJob := func(i int) {
+			MPIJobName := runningMPIJobs[i]
+			if ctx.Err() != nil {
+				return
+			}
+			MPIJob, err := ev.GetMPIJob(ctx, dynamicArgs().Namespace, MPIJobName)
+			if err != nil {
+				candidatesLock.Lock()
+				errs = append(errs, err)
+				candidatesLock.Unlock()
+				return
+			}
+
+			annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
+			if err != nil {
+				klog.Infof("Error loading annotations: %v", err)
+			}
+			if !found {
+				klog.Infof("Annotations not found")
+			}
+
+			if netScaleOut(annotations[util.ScaleOutAnnotation]) > 0 {
+				candidatesLock.Lock()
+				scaleOutMPIJobs = append(scaleOutMPIJobs, MPIJob)
+				candidatesLock.Unlock()
+			}
+		}
+		ev.parallelUntil(ctx, len(runningMPIJobs), checkJob)
+	}
+	if aggregated := utilerrors.NewAggregate(errs); aggregated != nil {
+		klog.Infof("Errors while collecting retrieve candidates: %v", aggregated)
+	}
+	// Concurrent collection appends in arbitrary order; re-sort so equal-cost
+	// candidates keep a deterministic plan across passes.
+	sort.Slice(backfilledPods, func(i, j int) bool {
+		return backfilledPods[i].Name < backfilledPods[j].Name
+	})
+	sort.Slice(scaleOutMPIJobs, func(i, j int) bool {
+		return scaleOutMPIJobs[i].GetName() < scaleOutMPIJobs[j].GetName()
+	})
+
+	pdbs, err := getPodDisruptionBudgets(ev.PdbLister)
+	if err != nil {
+		klog.Infof("PDB load error: %v", err)
+	}
+
+	// A scale-in-only policy leaves backfilled pods alone entirely.
+	if dynamicArgs().RetrievePolicy == RetrievePolicyScaleInOnly {
+		backfilledPods = nil
+	}
+	for i := 0; i < len(backfilledPods); i++ {
+		var backfilledInfo []int
+		backfilledInfo = append(backfilledInfo, 0)
+		backfilledInfo = append(backfilledInfo, i)
+		// Victims migrated to DRA free their GPUs through claims instead.
+		victimGPUs := int(podAcceleratorRequest(&backfilledPods[i]) + ev.podDRAGPUs(ctx, &backfilledPods[i]))
+		backfilledInfo = append(backfilledInfo, victimGPUs)
+		// A victim with an unknown model ranks as zero throughput; its
+		// retraction stays possible either way. A pod with no model-name at
+		// all is a fixed-size workload, not a table miss: it keeps its
+		// default eviction cost and never consults the table.
+		victimThroughput := 0
+		if modelName := backfilledPods[i].Annotations[util.ModelNameAnnotation]; modelName == "" {
+			ev.noteMissingModelAnnotation(&backfilledPods[i])
+		} else if profile, knownModel := profileForModelBatch(scalableModelData, modelName, backfilledPods[i].Annotations[batchSizeAnnotation]); knownModel && victimGPUs < len(profile) {
+			victimThroughput = int(profile[victimGPUs])
+		} else if !knownModel {
+			ev.noteUnknownModel(podNow, modelName)
+		}
+		backfilledInfo = append(backfilledInfo, victimThroughput)
+		if podViolatesPDB(&backfilledPods[i], pdbs) {
+			backfilledInfo = append(backfilledInfo, 1)
+		} else {
+			backfilledInfo = append(backfilledInfo, 0)
+		}
+		evictionCost := int(PodEvictionCost(&backfilledPods[i], weights))
+		// A victim busy on its GPUs costs more to retract than one idling
+		// them, when a utilization source is configured.
+		if utilization, check := ev.podGPUUtilization(ctx, &backfilledPods[i]); check {
+			evictionCost += int(utilization)
+		}
+		backfilledInfo = append(backfilledInfo, evictionCost)
+		backfilledInfo = append(backfilledInfo, deadlineDistanceSeconds(&backfilledPods[i]))
+		victimPriority := 0
+		if backfilledPods[i].Spec.Priority != nil {
+			victimPriority = int(*backfilledPods[i].Spec.Priority)
+		}
+		backfilledInfo = append(backfilledInfo, victimPriority)
+		// Run time measures the work lost on retraction; a pod that barely
+		// started is a better victim than one hours into its job.
+		victimRuntime := 0
+		if backfilledPods[i].Status.StartTime != nil {
+			victimRuntime = int(time.Since(backfilledPods[i].Status.StartTime.Time).Seconds())
+		}
+		backfilledInfo = append(backfilledInfo, victimRuntime)
+		retrieveCandidates = append(retrieveCandidates, backfilledInfo)
+	}
+	for i := 0; i < len(scaleOutMPIJobs); i++ {
+		var scaleInfo []int
+		scaleInfo = append(scaleInfo, 1)
+		scaleInfo = append(scaleInfo, i)
+
+		replicas, found, err := unstructured.NestedInt64(scaleOutMPIJobs[i].Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+		if err != nil {
+			klog.Infof("Error reading replicas: %v", err)
+			continue
+		}
+		if !found {
+			klog.Infof("Replicas not found")
+			continue
+		}
+		annotations, found, err := unstructured.NestedStringMap(scaleOutMPIJobs[i].Object, "metadata", "annotations")
+		if err != nil {
+			klog.Infof("Error loading annotations: %v", err)
+		}
+		if !found {
+			klog.Infof("Annotations not found")
+		}
+		scaleOutGpus := int(netScaleOut(annotations[util.ScaleOutAnnotation]))
+
+		// Never shrink below the job's co-scheduling floor. The group size is
+		// workers + 1 for the launcher.
+		minMember := mpiJobMinMember(scaleOutMPIJobs[i])
+		maxShrink := replicas + 1 - minMember
+		// The job's own declared floor can sit above the co-scheduling one.
+		minWorkers, _ := mpiJobWorkerBounds(scaleOutMPIJobs[i])
+		if byBounds := replicas - minWorkers; byBounds < maxShrink {
+			maxShrink = byBounds
+		}
+		if maxShrink <= 0 {
+			continue
+		}
+		if int64(scaleOutGpus) > maxShrink {
+			scaleOutGpus = int(maxShrink)
+		}
+		// Frameworks that cannot shrink, or only in certain steps, bound
+		// how much of the scale-out can be taken back.
+		scaleOutGpus = int(parseScaleConstraints(annotations).clampShrink(replicas, int64(scaleOutGpus)))
+		if scaleOutGpus <= 0 {
+			continue
+		}
+
+		// Without a throughput profile the loss of a scale-in cannot be
+		// estimated, so the job is not shrunk.
+		profile, knownModel := profileForModelBatch(scalableModelData, annotations[util.ModelNameAnnotation], annotations[batchSizeAnnotation])
+		if !knownModel {
+			ev.noteUnknownModel(podNow, annotations[util.ModelNameAnnotation])
+			continue
+		}
+		// A job scaled past the profile's tail (user input, never validated
+		// against the table) has no defined loss; skip it rather than index
+		// out of range.
+		if int(replicas) >= len(profile) || int(replicas)-scaleOutGpus < 0 {
+			continue
+		}
+		s1 := profile[int(replicas)]
+		s2 := profile[int(replicas)-scaleOutGpus]
+
+		scaleInfo = append(scaleInfo, scaleOutGpus)
+		scaleInfo = append(scaleInfo, int(s1-s2))
+		// Scale-in shrinks a job instead of evicting a budgeted pod; its cost
+		// is the marginal throughput given back.
+		scaleInfo = append(scaleInfo, 0)
+		scaleInfo = append(scaleInfo, int((s1-s2)*weights.Runtime))
+		// Scale-in has no deadline of its own.
+		scaleInfo = append(scaleInfo, noDeadline)
+		scaleInfo = append(scaleInfo, int(ev.mpiJobPriority(ctx, scaleOutMPIJobs[i])))
+		// Scale-in loses no completed work: the job keeps running smaller.
+		scaleInfo = append(scaleInfo, 0)
+		retrieveCandidates = append(retrieveCandidates, scaleInfo)
+	}
+
+	if len(retrieveCandidates) == 0 {
+		return plan
+	}
+
+	sortRetrieveCandidates(retrieveCandidates)
+
+	checkTemp := false
+	needGPUsTemp := plan.NeedGPUs
+	for _, candidate := range retrieveCandidates {
+		needGPUsTemp -= candidate[2]
+		if needGPUsTemp <= 0 {
+			checkTemp = true
+			break
+		}
+	}
+	if !checkTemp {
+		return plan
+	}
+
+	// Prefer an exact minimal-cost set over greedy over-retraction.
+	if optimalSet := selectMinimalRetractionSet(retrieveCandidates, plan.NeedGPUs); optimalSet != nil {
+		retrieveCandidates = optimalSet
+	}
+
+	needGPUsTemp = plan.NeedGPUs
+	for _, candidate := range retrieveCandidates {
+		action := RetrieveAction{
+			GPUsFreed:      candidate[2],
+			ThroughputLoss: candidate[3],
+		}
+		if candidate[0] == 0 {
+			action.Retract = true
+			action.VictimPod = backfilledPods[candidate[1]].DeepCopy()
+			action.ForceDelete = forceDeleteNodes[action.VictimPod.Spec.NodeName]
+		} else {
+			action.MPIJobName = scaleOutMPIJobs[candidate[1]].GetName()
+		}
+		plan.Actions = append(plan.Actions, action)
+		plan.GPUsFreed += action.GPUsFreed
+		plan.ThroughputLoss += action.ThroughputLoss
+		needGPUsTemp -= candidate[2]
+		if needGPUsTemp <= 0 {
+			plan.Feasible = true
+			break
+		}
+	}
+	return plan
+}
+
+// noDeadline sorts a candidate without a declared deadline as infinitely far
+// from one, i.e. freely retractable on the deadline dimension.
+const noDeadline = int(^uint(0) >> 1)
+
+// deadlineDistanceSeconds returns how many seconds the pod has left until
+// its declared expected completion time; pods without (or with a malformed)
+// deadline count as infinitely far from one.
+func deadlineDistanceSeconds(pod *v1.Pod) int {
+	deadlineStr, check := pod.Annotations[ImminentCompletionAnnotation]
+	if !check {
+		return noDeadline
+	}
+	deadline, err := time.Parse(time.RFC3339, deadlineStr)
+	if err != nil {
+		klog.Infof("Ignoring malformed %v on %v: %v", ImminentCompletionAnnotation, pod.Name, err)
+		return noDeadline
+	}
+	return int(time.Until(deadline).Seconds())
+}
+
+// nodeReadyStatus reports whether the node's Ready condition is True, and
+// when it stopped being True. An Unknown condition counts as not ready: the
+// kubelet is unreachable and eviction cannot be acknowledged.
+func nodeReadyStatus(node *v1.Node) (bool, time.Time) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != v1.NodeReady {
+			continue
+		}
+		if condition.Status == v1.ConditionTrue {
+			return true, time.Time{}
+		}
+		return false, condition.LastTransitionTime.Time
+	}
+	return false, time.Time{}
+}
+
+// notReadyForceDeleteAllowed reports whether victims on a node NotReady
+// since <notReadySince> may be force-deleted under the configured fencing
+// timeout.
+func notReadyForceDeleteAllowed(notReadySince time.Time) bool {
+	if dynamicArgs().NotReadyForceDeleteSeconds <= 0 || notReadySince.IsZero() {
+		return false
+	}
+	return time.Since(notReadySince) >= time.Duration(dynamicArgs().NotReadyForceDeleteSeconds)*time.Second
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// planStartExpiry drops tracking for preemptors that never get bound, e.g.
+// because they were deleted while pending.
+const planStartExpiry = 30 * time.Minute
+
+// planStart remembers when the Dynamic path started working for a pod and
+// what kind of plan it executed, so the full latency to the pod's bind can
+// be observed.
+type planStart struct {
+	start  time.Time
+	action string
+}
+
+var (
+	planStartsLock sync.Mutex
+	planStarts     = make(map[string]planStart)
+)
+
+// planActionLabel summarizes a plan for the latency breakdown: "retract",
+// "scale-in", or "mixed".
+func planActionLabel(plan *RetrievePlan) string {
+	retracts, scaleIns := 0, 0
+	for _, action := range plan.Actions {
+		if action.Retract {
+			retracts++
+		} else {
+			scaleIns++
+		}
+	}
+	switch {
+	case retracts > 0 && scaleIns > 0:
+		return "mixed"
+	case scaleIns > 0:
+		return "scale-in"
+	default:
+		return "retract"
+	}
+}
+
+// markPlanTriggered stamps the start of a Dynamic PostFilter pass for the
+// pod, unless an earlier pass is still being measured: the latency of
+// interest runs from the first trigger, not the most recent retry.
+func markPlanTriggered(pod *v1.Pod) {
+	planStartsLock.Lock()
+	defer planStartsLock.Unlock()
+	for key, entry := range planStarts {
+		if time.Since(entry.start) > planStartExpiry {
+			delete(planStarts, key)
+		}
+	}
+	key := pod.Namespace + "/" + pod.Name
+	if _, check := planStarts[key]; check {
+		return
+	}
+	planStarts[key] = planStart{start: time.Now()}
+}
+
+// markPlanExecuted records that a plan of kind <action> was executed for the
+// preemptor, keeping the original trigger timestamp.
+func markPlanExecuted(pod *v1.Pod, action string) {
+	planStartsLock.Lock()
+	defer planStartsLock.Unlock()
+	key := pod.Namespace + "/" + pod.Name
+	entry, check := planStarts[key]
+	if !check {
+		entry = planStart{start: time.Now()}
+	}
+	entry.action = action
+	planStarts[key] = entry
+}
+
+// ObservePlanToBound completes the measurement when the preemptor is bound,
+// exporting the full trigger-to-bound latency under the plan's action type.
+// Pods that got bound without an executed plan are dropped unmeasured.
+func ObservePlanToBound(pod *v1.Pod) {
+	key := pod.Namespace + "/" + pod.Name
+	planStartsLock.Lock()
+	entry, check := planStarts[key]
+	if check {
+		delete(planStarts, key)
+	}
+	planStartsLock.Unlock()
+	if !check || entry.action == "" {
+		return
+	}
+	metrics.PlanToBoundSeconds.WithLabelValues(entry.action).Observe(time.Since(entry.start).Seconds())
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// nodeNameIndex is the informer index mapping spec.nodeName to pods, so the
+// planning paths stop issuing one field-selector pod list per node and per
+// cycle against the apiserver.
+const nodeNameIndex = "spec.nodeName"
+
+var (
+	podIndexLock sync.RWMutex
+	podIndexer   cache.Indexer
+)
+
+// InitPodNodeIndex installs the nodeName index on the shared pod informer
+// and remembers its indexer. It is idempotent so multiple scheduler profiles
+// can call it.
+func InitPodNodeIndex(informer cache.SharedIndexInformer) error {
+	podIndexLock.Lock()
+	defer podIndexLock.Unlock()
+	if podIndexer != nil {
+		return nil
+	}
+	if _, check := informer.GetIndexer().GetIndexers()[nodeNameIndex]; !check {
+		if err := informer.AddIndexers(cache.Indexers{nodeNameIndex: func(obj interface{}) ([]string, error) {
+			pod, ok := obj.(*v1.Pod)
+			if !ok {
+				return nil, fmt.Errorf("expected *v1.Pod, got %T", obj)
+			}
+			if pod.Spec.NodeName == "" {
+				return nil, nil
+			}
+			return []string{pod.Spec.NodeName}, nil
+		}}); err != nil {
+			return err
+		}
+	}
+	podIndexer = informer.GetIndexer()
+	return nil
+}
+
+// snapshotCyclePods lists the cluster's pods once and groups them by node,
+// so every subroutine of the same Dynamic call works from one consistent
+// view instead of re-reading pods per node and per subroutine. The map
+// lives only on this Evaluator, i.e. for a single PostFilter call.
+func (ev *Evaluator) snapshotCyclePods(ctx context.Context) {
+	if ev.Snapshot != nil {
+		return
+	}
+	var pods []*v1.Pod
+	podIndexLock.RLock()
+	indexer := podIndexer
+	podIndexLock.RUnlock()
+	if indexer != nil {
+		for _, obj := range indexer.List() {
+			if pod, ok := obj.(*v1.Pod); ok {
+				pods = append(pods, pod)
+			}
+		}
+	} else {
+		list, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			klog.Infof("Failed to snapshot pods for the cycle: %v", err)
+			return
+		}
+		for i := range list.Items {
+			pods = append(pods, &list.Items[i])
+		}
+	}
+	ev.cyclePods = make(map[string][]*v1.Pod, len(pods))
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			ev.cyclePods[pod.Spec.NodeName] = append(ev.cyclePods[pod.Spec.NodeName], pod)
+		}
+	}
+}
+
+// podsOnNode returns the pods bound to <nodeName>: from the cycle snapshot
+// when one was taken, from the informer index when it is wired, and from
+// the apiserver otherwise.
+func (ev *Evaluator) podsOnNode(ctx context.Context, nodeName string) ([]*v1.Pod, error) {
+	if ev.Snapshot != nil {
+		return ev.Snapshot.podsOnNode(nodeName), nil
+	}
+	if ev.cyclePods != nil {
+		return ev.cyclePods[nodeName], nil
+	}
+	podIndexLock.RLock()
+	indexer := podIndexer
+	podIndexLock.RUnlock()
+	if indexer != nil {
+		objs, err := indexer.ByIndex(nodeNameIndex, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		pods := make([]*v1.Pod, 0, len(objs))
+		for _, obj := range objs {
+			if pod, ok := obj.(*v1.Pod); ok {
+				pods = append(pods, pod)
+			}
+		}
+		return pods, nil
+	}
+	list, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName)})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*v1.Pod, 0, len(list.Items))
+	for i := range list.Items {
+		pods = append(pods, &list.Items[i])
+	}
+	return pods, nil
+}
@@ -20,9 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -35,13 +33,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apiserver/pkg/util/feature"
-	"k8s.io/client-go/dynamic"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	policylisters "k8s.io/client-go/listers/policy/v1"
-	"k8s.io/client-go/tools/clientcmd"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	"k8s.io/klog/v2"
 	extenderv1 "k8s.io/kube-scheduler/extender/v1"
@@ -49,6 +45,7 @@ import (
 	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/parallelize"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/names"
 	"k8s.io/kubernetes/pkg/scheduler/metrics"
 	"k8s.io/kubernetes/pkg/scheduler/util"
 )
@@ -139,52 +136,121 @@ type Evaluator struct {
 	PodLister  corelisters.PodLister
 	PdbLister  policylisters.PodDisruptionBudgetLister
 	State      *framework.CycleState
+	// Snapshot, when set, feeds pods and elastic jobs from a recorded
+	// cluster state instead of the API, for offline simulation.
+	Snapshot *ClusterSnapshot
+	// cyclePods is the per-node pod view taken once at the start of a
+	// Dynamic call and shared by its subroutines; see snapshotCyclePods.
+	cyclePods map[string][]*v1.Pod
 	Interface
 }
 
 func (ev *Evaluator) Dynamic(ctx context.Context, pod *v1.Pod, m framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
-	scalableModelData := make(map[string][]float64)
-	scalableModelData["VGG16"] = []float64{4.232, 3.499, 4.61, 4.526, 5.396, 6.363}
-	scalableModelData["VGG19"] = []float64{3.693, 2.793, 3.6294, 4.3105, 5.2051, 5.934}
-	scalableModelData["inceptionv3"] = []float64{3.5, 5.495, 6.29, 8.449, 9.693, 11.262}
-	scalableModelData["alex-net"] = []float64{15.969, 7.496, 9.002, 10.572, 13.005, 14.708}
-	scalableModelData["resnet50"] = []float64{6.78098, 7.777, 12.4701, 12.18717, 15.90254, 18.968}
-	scalableModelData["resnet101"] = []float64{4.253, 5.519, 8.863, 8.8171, 9.686, 12.904}
 	var requestGPUs int64
 
-	nodes, err := ev.Handler.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	if err != nil {
-		klog.Infof("Node info error")
-	}
+	markPlanTriggered(pod)
+
+	// However this cycle ends — plan executed, nothing to retract, backoff —
+	// its retraction decision is settled, so pods held at the Backfill Permit
+	// only wait out their fallback timer for nothing past this point.
+	defer ev.releaseBackfillHolds()
 
-	for _, container := range pod.Spec.Containers {
-		if gpuRequest, ok := container.Resources.Requests["nvidia.com/gpu"]; ok {
-			requestGPUs = int64(gpuRequest.Value())
+	// Planning API calls are bounded by the configured deadline; only the
+	// execution of an already-chosen plan runs on the cycle's own context.
+	planningCtx, cancelPlanning := context.WithTimeout(ctx, planningDeadline())
+	defer cancelPlanning()
+
+	nodes, err := ev.Handler.ClientSet().CoreV1().Nodes().List(planningCtx, metav1.ListOptions{})
+	if err != nil {
+		// A deadline expiry or transient API error leaves nothing to plan
+		// against; the pod retries on a later cycle.
+		klog.Infof("Failed to list nodes for elastic planning: %v", err)
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, "Elastic planning could not list nodes")
+	}
+	// One pod scan feeds ListRunningMPIJob, the idle-GPU walk and Retrieve
+	// below, so they all plan against the same cluster state.
+	ev.snapshotCyclePods(planningCtx)
+
+	// Scale-out workers should land next to the fastest interconnect, so the
+	// throughput profile is looked up for the best class among GPU nodes.
+	interconnectClass := clusterInterconnectClass(nodes)
+	scalableModelData := throughputProfileForClass(interconnectClass)
+
+	requestGPUs = podAcceleratorRequest(pod)
+	// Pods migrated to DRA carry their GPU demand as ResourceClaims.
+	requestGPUs += ev.podDRAGPUs(planningCtx, pod)
+	// A pod that failed gang admission carries the whole group's demand, so
+	// retraction frees room for the gang instead of a single member pod.
+	if gangRequest, check := pod.Annotations["gang-request-gpus"]; check {
+		if gangRequestGPUs, err := strconv.ParseInt(gangRequest, 10, 64); err == nil && gangRequestGPUs > requestGPUs {
+			requestGPUs = gangRequestGPUs
 		}
 	}
-	runningMPIJobs := ev.ListRunningMPIJob(ctx, nodes)
+	runningMPIJobs := ev.ListRunningMPIJob(planningCtx, nodes)
+	// The cycle's node snapshot already tracks requested resources; the API
+	// walk remains as the fallback outside a scheduling cycle.
+	idleGPUs, err := ev.IdleGPUsFromSnapshot()
 	if err != nil {
-		klog.Fatalf("Failed to list MPIJobs: %s", err.Error())
+		idleGPUs = ev.idleGPUsinNodes(planningCtx, nodes)
+	}
+	if planningCtx.Err() != nil {
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic planning deadline exceeded", requestGPUs, idleGPUs, nil))
 	}
-	idleGPUs := ev.idleGPUsinNodes(ctx, nodes)
-	resourceRetrieve, allocatableNodeName := ev.Retrieve(ctx, pod, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs)
+
+	// Guaranteed progress mode: a pod gets at most one executed elastic plan
+	// per backoff period, everything past this point has side effects.
+	if !allowElasticAttempt(pod) {
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic attempt suppressed by backoff", requestGPUs, idleGPUs, nil))
+	}
+	planUID := string(uuid.NewUUID())
+	resourceRetrieve, allocatableNodeName, plan := ev.Retrieve(ctx, pod, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs, planUID)
 
 	if resourceRetrieve {
 		return framework.NewPostFilterResultWithNominatedNode(allocatableNodeName), framework.NewStatus(framework.Success)
 	}
 
 	if idleGPUs == 0 {
-		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, "Nothing can do")
+		ev.requestGPUProvisioning(planningCtx, pod, requestGPUs)
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, "Insufficient idle GPUs: "+ev.gpuShortageDiagnostics(planningCtx, nodes, plan, requestGPUs, idleGPUs))
+	}
+
+	// Scale-out needs the MPIJob CRD; in degraded mode backfill and
+	// retraction above are all the scheduler can offer.
+	if !ElasticJobsAvailable() {
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic scale disabled, MPIJob CRD absent", requestGPUs, idleGPUs, plan))
+	}
+
+	// Namespace-scoped mode never grows jobs living outside the
+	// preemptor's namespace.
+	if !namespaceInScope(pod, dynamicArgs().Namespace) {
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic scale disabled outside the preemptor's namespace", requestGPUs, idleGPUs, plan))
 	}
 
-	var scaleOutMPIJobName string
-	maxThroughput := 0.0
+	// A retract-only profile never grows jobs on behalf of its pods.
+	profile := ev.profileName()
+	if profilePolicy(profile).RetractOnly {
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic scale disabled for profile "+profile, requestGPUs, idleGPUs, plan))
+	}
+
+	var scaleOutCandidates []scaleOutCandidate
 	for _, MPIJobName := range runningMPIJobs {
-		MPIJob, err := ev.GetMPIJob(ctx, "my-ns", MPIJobName)
+		if planningCtx.Err() != nil {
+			return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("elastic planning deadline exceeded", requestGPUs, idleGPUs, plan))
+		}
+		MPIJob, err := ev.GetMPIJob(planningCtx, dynamicArgs().Namespace, MPIJobName)
 		if err != nil {
 			klog.Infof("Failed to get MPIJob: %v", err)
 		}
 
+		// Jobs over their GPU-hour budget are no longer scale-out eligible.
+		if mpiJobBudgetExhausted(MPIJob) {
+			continue
+		}
+		// A completing, failed or suspended job cannot use another worker.
+		if !elasticJobRunning(MPIJob) {
+			continue
+		}
+
 		annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
 		if err != nil {
 			klog.Infof("Error loading annotations: %v", err)
@@ -193,162 +259,476 @@ func (ev *Evaluator) Dynamic(ctx context.Context, pod *v1.Pod, m framework.NodeT
 			klog.Infof("Error finding annotations: %v", err)
 		}
 
-		usingGPUs, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+		replicas, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
 		if err != nil {
 			klog.Infof("Error reading replicas: %v", err)
 		}
 		if !found {
 			klog.Infof("Replicas not found")
 		}
-		usingGPUs += 1
-		if usingGPUs > 5 {
+		// The framework may only support certain worker counts or step
+		// sizes; the smallest allowed growth replaces the fixed +1, bounded
+		// by the job's own declared maximum.
+		_, maxWorkers := mpiJobWorkerBounds(MPIJob)
+		constraints := parseScaleConstraints(annotations)
+		growDelta := constraints.nextGrowDelta(replicas, maxWorkers)
+		if growDelta == 0 {
+			continue
+		}
+		launcherGPUs := mpiJobLauncherGPUs(MPIJob)
+		usingGPUs := replicas + launcherGPUs + growDelta
+		if usingGPUs > dynamicArgs().MaxWorkerReplicas {
 			continue
 		}
-		if maxThroughput < (scalableModelData[annotations["model-name"]][usingGPUs] - scalableModelData[annotations["model-name"]][usingGPUs-1]) {
-			if _, check := annotations["scale-out"]; !check {
-				maxThroughput = scalableModelData[annotations["model-name"]][usingGPUs] - scalableModelData[annotations["model-name"]][usingGPUs-1]
-				scaleOutMPIJobName = MPIJobName
+		modelProfile, knownModel := profileForModelBatch(scalableModelData, annotations[util.ModelNameAnnotation], annotations[batchSizeAnnotation])
+		if !knownModel {
+			ev.noteUnknownModel(pod, annotations[util.ModelNameAnnotation])
+			if dynamicArgs().UnknownModelPolicy == UnknownModelUnschedulable {
+				return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Model %q of MPIJob %v is absent from the throughput table", annotations[util.ModelNameAnnotation], MPIJobName))
 			}
+			continue
 		}
+		// The profile (arbitrary length when loaded from a ModelSource file)
+		// must cover the grown worker count; MaxWorkerReplicas alone does not
+		// guarantee that.
+		if usingGPUs >= int64(len(modelProfile)) || usingGPUs-growDelta < 0 {
+			continue
+		}
+		marginal := modelProfile[usingGPUs] - modelProfile[usingGPUs-growDelta]
+		if marginal <= 0 {
+			continue
+		}
+		// Several idle GPUs can be absorbed in one decision: keep extending
+		// the increment while the throughput curve still gains, capped by
+		// the configured step, the idle GPUs and the job's max replicas.
+		for growDelta < dynamicArgs().MaxScaleOutStep && growDelta < idleGPUs {
+			next := constraints.nextGrowDelta(replicas+growDelta, maxWorkers)
+			if next == 0 || growDelta+next > dynamicArgs().MaxScaleOutStep || growDelta+next > idleGPUs {
+				break
+			}
+			using := replicas + launcherGPUs + growDelta + next
+			if using > dynamicArgs().MaxWorkerReplicas || using >= int64(len(modelProfile)) {
+				break
+			}
+			gain := modelProfile[using] - modelProfile[using-next]
+			if gain <= 0 {
+				break
+			}
+			growDelta += next
+			marginal += gain
+		}
+		// Compute-bound jobs get priority for extra workers; a job already
+		// idling its GPUs gains little from another one.
+		if utilization, check := ev.jobGPUUtilization(planningCtx, MPIJobName); check {
+			marginal *= utilization / 100
+		}
+		// Under cost-aware selection, throughput-per-dollar is what
+		// competes, so cheap spot capacity wins over pricey on-demand.
+		if costAwareScaleOutFor(profile) {
+			marginal /= ev.jobCostPerGPUHour(planningCtx, nodes, MPIJobName)
+		}
+		// Jobs still holding scheduler-added replicas are not grown further.
+		if netScaleOut(annotations[util.ScaleOutAnnotation]) > 0 {
+			continue
+		}
+		// The extra worker must have an acceptable node to land on; an
+		// all-reduce ring stretched over a slow link loses more than the
+		// extra GPU gains.
+		if !ev.scaleOutPlacementFeasible(planningCtx, nodes, MPIJobName) {
+			continue
+		}
+		scaleOutCandidates = append(scaleOutCandidates, scaleOutCandidate{
+			name:         MPIJobName,
+			delta:        growDelta,
+			marginal:     marginal,
+			owner:        jobOwnerKey(MPIJob),
+			replicas:     replicas,
+			profile:      modelProfile,
+			launcherGPUs: mpiJobLauncherGPUs(MPIJob),
+			maxWorkers:   maxWorkers,
+			constraints:  constraints,
+		})
 	}
-	if maxThroughput > 0.0 {
-		ev.MPIJobScaling(ctx, "my-ns", scaleOutMPIJobName, 1)
-	}
-	return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, "Scale-Out MPIJob")
+	if dynamicArgs().BatchScaleOut {
+		// Batch mode distributes every idle GPU across eligible jobs in one
+		// pass instead of growing one job by one step per cycle.
+		if deltas := batchScaleOutPlan(scaleOutCandidates, idleGPUs); len(deltas) > 0 {
+			metrics.IdleGPUsUnabsorbed.Set(0)
+			for _, allocation := range deltas {
+				auditAnnotations := scaleAuditAnnotations(planUID, pod, "scale-out")
+				for i := range scaleOutCandidates {
+					if scaleOutCandidates[i].name != allocation.name {
+						continue
+					}
+					granted := scaleOutCandidates[i]
+					granted.delta = allocation.delta
+					auditAnnotations = withScaleExplanation(auditAnnotations, scaleOutExplanation(pod, &granted, scaleOutCandidates))
+					break
+				}
+				ev.MPIJobScaling(ctx, dynamicArgs().Namespace, allocation.name, allocation.delta, auditAnnotations)
+			}
+			markPlanExecuted(pod, "scale-out")
+			markElasticAttempt(pod)
+			metrics.DynamicPlansExecuted.WithLabelValues(profile, "scale-out").Inc()
+			ev.recordSchedulingDecision(ctx, planUID, pod, "scale-out", nil, deltas)
+			return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage(fmt.Sprintf("grew %d MPIJob(s) to absorb idle GPUs; pod waits for the next cycle", len(deltas)), requestGPUs, idleGPUs, nil))
+		}
+	} else if chosen := pickScaleOutCandidate(scaleOutCandidates, scaleOutPolicyFor(profile)); chosen != nil {
+		metrics.IdleGPUsUnabsorbed.Set(0)
+		ev.MPIJobScaling(ctx, dynamicArgs().Namespace, chosen.name, chosen.delta, withScaleExplanation(scaleAuditAnnotations(planUID, pod, "scale-out"), scaleOutExplanation(pod, chosen, scaleOutCandidates)))
+		markPlanExecuted(pod, "scale-out")
+		markElasticAttempt(pod)
+		metrics.DynamicPlansExecuted.WithLabelValues(profile, "scale-out").Inc()
+		ev.recordSchedulingDecision(ctx, planUID, pod, "scale-out", nil, []scaleOutAllocation{{name: chosen.name, delta: chosen.delta}})
+		return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage(fmt.Sprintf("grew MPIJob %s by %d worker(s) to absorb idle GPUs; pod waits for the next cycle", chosen.name, chosen.delta), requestGPUs, idleGPUs, nil))
+	}
+	ev.noteUnabsorbedIdleGPUs(pod, idleGPUs)
+	ev.requestGPUProvisioning(planningCtx, pod, requestGPUs)
+	return framework.NewPostFilterResultWithNominatedNode(""), framework.NewStatus(framework.Unschedulable, dynamicStatusMessage("idle GPUs unabsorbed, all elastic jobs at their scaling ceiling", requestGPUs, idleGPUs, plan))
 }
 
-func getPodTimestamp(pod *v1.Pod) metav1.Time {
-	if timestampStr, ok := pod.ObjectMeta.Annotations["retract-check-var"]; ok {
-		if timestamp, err := time.Parse(time.RFC3339, timestampStr); err == nil {
-			return metav1.Time{Time: timestamp}
+// releaseBackfillHolds lifts the Backfill plugin's Permit hold from every
+// still-waiting pod. By the time this runs the cycle's retraction decision
+// is made and its victims were already rejected, so the survivors bind now
+// instead of waiting out the fallback timer. Allowing a plugin a pod never
+// waited on is a no-op, so pods held by other Permit plugins are untouched.
+func (ev *Evaluator) releaseBackfillHolds() {
+	if ev.Handler == nil {
+		return
+	}
+	ev.Handler.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		StopBackfillHoldTimer(waitingPod.GetPod().UID)
+		waitingPod.Allow(names.Backfill)
+	})
+}
+
+// Aggregated accounting for cycles where idle GPUs sat unused because no
+// elastic job could grow; at most one summary event per hour.
+var (
+	unabsorbedLock      sync.Mutex
+	unabsorbedCycles    int
+	unabsorbedMaxIdle   int64
+	lastUnabsorbedEvent time.Time
+)
+
+// noteUnabsorbedIdleGPUs records that <idleGPUs> could not be absorbed this
+// cycle and, at most hourly, emits an event summarizing the wasted capacity
+// so operators see it without scraping metrics.
+func (ev *Evaluator) noteUnabsorbedIdleGPUs(pod *v1.Pod, idleGPUs int64) {
+	metrics.IdleGPUsUnabsorbed.Set(float64(idleGPUs))
+	unabsorbedLock.Lock()
+	defer unabsorbedLock.Unlock()
+	unabsorbedCycles++
+	if idleGPUs > unabsorbedMaxIdle {
+		unabsorbedMaxIdle = idleGPUs
+	}
+	if time.Since(lastUnabsorbedEvent) < time.Hour {
+		return
+	}
+	ev.Handler.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "IdleGPUsUnabsorbed", "ScaleOut", "%d cycles in the last hour left idle GPUs unabsorbed (up to %d at once): every elastic job was at its scaling ceiling", unabsorbedCycles, unabsorbedMaxIdle)
+	unabsorbedCycles = 0
+	unabsorbedMaxIdle = 0
+	lastUnabsorbedEvent = time.Now()
+}
+
+// elasticBackoffPeriod is the window within which a pending pod may execute
+// at most one elastic plan (retraction or scale mutation), so alternating
+// scale-out/retrieve/unschedulable loops cannot thrash the cluster.
+var (
+	elasticAttemptsLock sync.Mutex
+	lastElasticAttempt  = make(map[string]time.Time)
+)
+
+// allowElasticAttempt reports whether the pod may execute an elastic plan in
+// this cycle. At most one executed plan per pod per backoff period is
+// allowed; suppressed attempts are counted. Only markElasticAttempt starts
+// the window, so a cycle whose plan never executed does not burn it.
+func allowElasticAttempt(pod *v1.Pod) bool {
+	key := pod.Namespace + "/" + pod.Name
+	elasticAttemptsLock.Lock()
+	defer elasticAttemptsLock.Unlock()
+	// Entries past their window no longer suppress anything; drop them so
+	// the map does not grow with every pod ever planned for.
+	for staleKey, last := range lastElasticAttempt {
+		if time.Since(last) >= elasticBackoff() {
+			delete(lastElasticAttempt, staleKey)
 		}
 	}
-	return pod.ObjectMeta.CreationTimestamp
+	if last, check := lastElasticAttempt[key]; check && time.Since(last) < elasticBackoff() {
+		metrics.ElasticAttemptsSuppressed.Inc()
+		return false
+	}
+	return true
 }
 
-func (ev *Evaluator) Retrieve(ctx context.Context, podNow *v1.Pod, scalableModelData map[string][]float64, nodes *v1.NodeList, idleGPUs int64, requestGPUs int64, runningMPIJobs []string) (bool, string) {
-	var backfilledPods []v1.Pod
-	var scaleOutMPIJobs []*unstructured.Unstructured
-	var retrieveCandidates [][]int
-	for _, node := range nodes.Items {
-		pods, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name)})
-		if err != nil {
-			klog.Infof("PodList load error")
-			continue
-		}
-		for _, pod := range pods.Items {
-			if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "backfilled" {
-				podNowCreationTimeStamp := getPodTimestamp(podNow)
-				podCreationTimeStamp := getPodTimestamp(&pod)
+// markElasticAttempt starts the pod's backoff window once a plan with side
+// effects really executed.
+func markElasticAttempt(pod *v1.Pod) {
+	elasticAttemptsLock.Lock()
+	defer elasticAttemptsLock.Unlock()
+	lastElasticAttempt[pod.Namespace+"/"+pod.Name] = time.Now()
+}
 
-				if podNowCreationTimeStamp.Before(&podCreationTimeStamp) {
-					backfilledPods = append(backfilledPods, pod)
-				}
-			}
-		}
+// scaleAuditAnnotations builds the audit trail attached to every scale and
+// retraction mutation, so API audit pipelines can correlate cluster mutations
+// with the scheduling decision that caused them.
+func scaleAuditAnnotations(planUID string, preemptor *v1.Pod, reason string) map[string]string {
+	return map[string]string{
+		"audit.scheduling.k8s.io/plan-uid":  planUID,
+		"audit.scheduling.k8s.io/preemptor": preemptor.Namespace + "/" + preemptor.Name,
+		"audit.scheduling.k8s.io/reason":    reason,
 	}
-	for _, MPIJobName := range runningMPIJobs {
-		MPIJob, err := ev.GetMPIJob(ctx, "my-ns", MPIJobName)
-		if err != nil {
-			klog.Infof("Failed to get MPIJob: %v", err)
-		}
+}
 
-		annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
-		if err != nil {
-			klog.Infof("Error reading replicas: %v", err)
-		}
-		if !found {
-			klog.Infof("Replicas not found")
-		}
+func getPodTimestamp(pod *v1.Pod) metav1.Time {
+	timestamp, _ := util.RetractionTimestamp(pod, pod.ObjectMeta.CreationTimestamp.Time)
+	return metav1.Time{Time: timestamp}
+}
+
+// Retrieve plans and executes the retractions and scale-ins freeing GPUs
+// for <podNow>. It returns whether a plan was executed, the node to
+// nominate, and the computed plan itself so the caller can explain an
+// infeasible verdict.
+func (ev *Evaluator) Retrieve(ctx context.Context, podNow *v1.Pod, scalableModelData map[string][]float64, nodes *v1.NodeList, idleGPUs int64, requestGPUs int64, runningMPIJobs []string, planUID string) (bool, string, *RetrievePlan) {
+	planningCtx, cancelPlanning := context.WithTimeout(ctx, planningDeadline())
+	defer cancelPlanning()
+	plan := ev.PlanRetrieve(planningCtx, podNow, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs)
+	recordPlan(podNow, plan)
+	ev.shadowEvaluate(planningCtx, podNow, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs, plan)
+	if planningCtx.Err() != nil {
+		klog.Infof("Retrieve planning cut short: %v", planningCtx.Err())
+		return false, "", plan
+	}
+	if !plan.Feasible {
+		return false, "", plan
+	}
+	// A job the operator expects to finish within the window frees its GPUs
+	// naturally; holding the plan beats retracting or shrinking others.
+	if jobName, imminent := ev.completionImminent(planningCtx, runningMPIJobs); imminent {
+		klog.Infof("Holding retrieve plan for %v: %v is expected to complete naturally", podNow.Name, jobName)
+		return false, "", plan
+	}
+	NewRetractExecutor(ev, plan, planUID, podNow).Execute(ctx)
+	markPlanExecuted(podNow, planActionLabel(plan))
+	markElasticAttempt(podNow)
+	metrics.DynamicPlansExecuted.WithLabelValues(ev.profileName(), planActionLabel(plan)).Inc()
+	ev.recordSchedulingDecision(ctx, planUID, podNow, planActionLabel(plan), plan, nil)
+	// With wait-for-effect on, the nomination is withheld until the victims
+	// are really gone; a later cycle finds the freed capacity itself.
+	if !ev.waitForPlanEffect(ctx, plan) {
+		klog.Infof("Plan %v effects not yet visible; nominating no node for %v", planUID, podNow.Name)
+		return true, "", plan
+	}
+	nominatedNodeName, fullFit := planNominatedNode(plan, requestGPUs)
+	// When one node's retractions cover the whole request, the freed GPUs
+	// are reserved through the nominator so no other pending pod schedules
+	// onto them before the preemptor's next attempt.
+	if fullFit {
+		ev.nominateOnFreedNode(ctx, podNow, nominatedNodeName)
+	}
+	return true, nominatedNodeName, plan
+}
 
-		if _, check := annotations["scale-out"]; check {
-			scaleOutMPIJobs = append(scaleOutMPIJobs, MPIJob)
+// planNominatedNode picks the node where the plan's retractions free the
+// most GPUs, so Dynamic can put a concrete node name into the
+// PostFilterResult and the freed capacity is reserved for the preemptor
+// instead of nominating an empty name. Scale-ins free GPUs on whatever
+// nodes the operator removes workers from, so only retractions count.
+// The second return reports whether that node alone frees the preemptor's
+// whole request.
+func planNominatedNode(plan *RetrievePlan, requestGPUs int64) (string, bool) {
+	freedPerNode := make(map[string]int)
+	for _, action := range plan.Actions {
+		if action.Retract && action.VictimPod.Spec.NodeName != "" {
+			freedPerNode[action.VictimPod.Spec.NodeName] += action.GPUsFreed
 		}
 	}
-
-	for i := 0; i < len(backfilledPods); i++ {
-		var backfilledInfo []int
-		var gpuRequest resource.Quantity
-		backfilledInfo = append(backfilledInfo, 0)
-		backfilledInfo = append(backfilledInfo, i)
-		for _, container := range backfilledPods[i].Spec.Containers {
-			gpuRequest = container.Resources.Requests["nvidia.com/gpu"]
+	allocatableNodeName := ""
+	maxFreed := 0
+	for nodeName, freed := range freedPerNode {
+		if freed > maxFreed {
+			maxFreed = freed
+			allocatableNodeName = nodeName
 		}
-		backfilledInfo = append(backfilledInfo, int(gpuRequest.Value()))
-		backfilledInfo = append(backfilledInfo, int(scalableModelData[backfilledPods[i].Annotations["model-name"]][int(gpuRequest.Value())]))
-		retrieveCandidates = append(retrieveCandidates, backfilledInfo)
 	}
-	for i := 0; i < len(scaleOutMPIJobs); i++ {
-		var scaleInfo []int
-		scaleInfo = append(scaleInfo, 1)
-		scaleInfo = append(scaleInfo, i)
+	return allocatableNodeName, allocatableNodeName != "" && int64(maxFreed) >= requestGPUs
+}
+
+// ImminentCompletionAnnotation is set by the MPI operator (or the job owner)
+// to the RFC3339 time the job is expected to finish naturally, e.g. at its
+// final epoch.
+const ImminentCompletionAnnotation = "expected-completion-time"
+
+// imminentCompletionWindow is how far ahead an expected completion counts as
+// imminent; imminentCompletionGrace bounds how long a passed expectation is
+// still honored before destructive plans resume.
+const (
+	imminentCompletionWindow = 2 * time.Minute
+	imminentCompletionGrace  = 30 * time.Second
+)
 
-		replicas, found, err := unstructured.NestedInt64(scaleOutMPIJobs[i].Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+// completionImminent reports whether any running MPIJob is expected to
+// complete within the imminent window, in which case destructive plans are
+// held. An expectation that blew past its grace period is ignored, so a
+// stale annotation cannot starve pending pods forever.
+func (ev *Evaluator) completionImminent(ctx context.Context, runningMPIJobs []string) (string, bool) {
+	for _, MPIJobName := range runningMPIJobs {
+		if ctx.Err() != nil {
+			return "", false
+		}
+		MPIJob, err := ev.GetMPIJob(ctx, dynamicArgs().Namespace, MPIJobName)
 		if err != nil {
-			log.Fatalf("Error reading replicas: %s", err.Error())
+			continue
 		}
-		if !found {
-			log.Fatalf("Replicas not found")
+		annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
+		if err != nil || !found {
+			continue
 		}
-		annotations, found, err := unstructured.NestedStringMap(scaleOutMPIJobs[i].Object, "metadata", "annotations")
-		if err != nil {
-			klog.Infof("Error reading replicas: %v", err)
+		expectedStr, check := annotations[ImminentCompletionAnnotation]
+		if !check {
+			continue
 		}
-		if !found {
-			klog.Infof("Replicas not found")
+		expected, parseErr := time.Parse(time.RFC3339, expectedStr)
+		if parseErr != nil {
+			klog.Infof("Ignoring malformed %v on %v: %v", ImminentCompletionAnnotation, MPIJobName, parseErr)
+			continue
+		}
+		if until := time.Until(expected); until < imminentCompletionWindow && until > -imminentCompletionGrace {
+			return MPIJobName, true
 		}
-		scaleOutGpus, _ := strconv.Atoi(annotations["scale-out"])
+	}
+	return "", false
+}
 
-		s1 := scalableModelData[annotations["model-name"]][int(replicas)]
-		s2 := scalableModelData[annotations["model-name"]][int(replicas)-scaleOutGpus]
+// podControllerWillRecreate reports whether a controller owns <pod> and will
+// recreate it as soon as the retraction deletes it. Retractions performed by
+// this scheduler re-queue behind the preemptor via retract-check-var, but a
+// controller replacement is a fresh pod with no such ordering hint.
+func podControllerWillRecreate(pod *v1.Pod) bool {
+	return metav1.GetControllerOf(pod) != nil
+}
 
-		scaleInfo = append(scaleInfo, scaleOutGpus)
-		scaleInfo = append(scaleInfo, int(s1-s2))
-		retrieveCandidates = append(retrieveCandidates, scaleInfo)
+// nominateOnFreedNode records <podNow> as nominated on <nodeName> so the
+// recreated victim sees the freed GPUs as spoken for and cannot win the race.
+// The entry is overwritten or cleared by the preemptor's next scheduling
+// attempt.
+func (ev *Evaluator) nominateOnFreedNode(ctx context.Context, podNow *v1.Pod, nodeName string) {
+	podInfo, err := framework.NewPodInfo(podNow)
+	if err != nil {
+		klog.Infof("Fail to build pod info for nomination: %v", err)
+		return
 	}
+	ev.Handler.AddNominatedPod(klog.FromContext(ctx), podInfo, &framework.NominatingInfo{
+		NominatingMode:    framework.ModeOverride,
+		NominatedNodeName: nodeName,
+	})
+	RecordNominatedGPUs(podNow, nodeName)
+}
 
-	if len(retrieveCandidates) == 0 {
-		return false, ""
+// shadowEvaluate recomputes the plan under the configured shadow policy and
+// reports where it diverges from the active plan. The shadow plan is never
+// executed.
+func (ev *Evaluator) shadowEvaluate(ctx context.Context, podNow *v1.Pod, scalableModelData map[string][]float64, nodes *v1.NodeList, idleGPUs int64, requestGPUs int64, runningMPIJobs []string, activePlan *RetrievePlan) {
+	shadowWeights := dynamicArgs().ShadowPolicy
+	if shadowWeights == nil {
+		return
 	}
+	shadowPlan := ev.planRetrieveWithWeights(ctx, podNow, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs, *shadowWeights)
+	if len(shadowPlan.Actions) != len(activePlan.Actions) {
+		metrics.ShadowPlanDivergence.WithLabelValues("victim_count").Inc()
+	}
+	if shadowPlan.GPUsFreed != activePlan.GPUsFreed {
+		metrics.ShadowPlanDivergence.WithLabelValues("gpus_freed").Inc()
+	}
+	klog.Infof("Shadow plan for %v: victims %d vs %d, GPUs freed %d vs %d", podNow.Name, len(shadowPlan.Actions), len(activePlan.Actions), shadowPlan.GPUsFreed, activePlan.GPUsFreed)
+}
 
-	needGPUs := int(requestGPUs - idleGPUs)
-	sort.SliceStable(retrieveCandidates, func(i, j int) bool {
-		return retrieveCandidates[i][3] < retrieveCandidates[j][3]
-	})
-	sort.SliceStable(retrieveCandidates, func(i, j int) bool {
-		return retrieveCandidates[i][2] < retrieveCandidates[j][2]
-	})
-
-	checkTemp := false
-	needGPUsTemp := needGPUs
-	for _, candidate := range retrieveCandidates {
-		needGPUsTemp -= candidate[2]
-		if needGPUsTemp <= 0 {
-			checkTemp = true
-			break
+// podViolatesPDB reports whether evicting <pod> right now would violate any
+// of the given PodDisruptionBudgets.
+func podViolatesPDB(pod *v1.Pod, pdbs []*policy.PodDisruptionBudget) bool {
+	for _, pdb := range pdbs {
+		if pdb.Namespace != pod.Namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed <= 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	if !checkTemp {
-		return false, ""
-	} else {
-		needGPUsTemp = needGPUs
-		for _, candidate := range retrieveCandidates {
-			if candidate[0] == 0 {
-				err := util.RetractPod(ctx, ev.Handler.ClientSet(), &backfilledPods[candidate[1]])
-				if err != nil {
-					klog.Infof("Fail retract: %v", err)
-				}
-			} else {
-				ev.MPIJobScaling(ctx, "my-ns", "tensorflow-mnist-elastic", -int64(candidate[2]))
-			}
-			needGPUsTemp -= candidate[2]
-			if needGPUsTemp <= 0 {
-				return true, ""
+// mpiJobMinMember returns the co-scheduling floor (launcher + workers) below
+// which the job must not be shrunk. It prefers the kubeflow scheduling policy
+// field and falls back to the min-available annotation, defaulting to 2
+// (launcher plus one worker).
+func mpiJobMinMember(MPIJob *unstructured.Unstructured) int64 {
+	minAvailable, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "runPolicy", "schedulingPolicy", "minAvailable")
+	if err == nil && found && minAvailable > 0 {
+		return minAvailable
+	}
+	annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
+	if err == nil && found {
+		if val, check := annotations["scheduling.k8s.io/min-available"]; check {
+			if minMember, convErr := strconv.ParseInt(val, 10, 64); convErr == nil && minMember > 0 {
+				return minMember
 			}
 		}
 	}
-	return false, ""
+	return 2
+}
+
+// mpiJobLauncherGPUs reads the launcher template's actual GPU request, so a
+// CPU-only launcher contributes zero to the job's GPU footprint instead of
+// the old replicas+1 heuristic. A spec without a readable launcher template
+// keeps the historical assumption of one GPU.
+func mpiJobLauncherGPUs(MPIJob *unstructured.Unstructured) int64 {
+	containers, found, err := unstructured.NestedSlice(MPIJob.Object, "spec", "mpiReplicaSpecs", "Launcher", "template", "spec", "containers")
+	if err != nil || !found {
+		return 1
+	}
+	var launcherGPUs int64
+	for _, containerObj := range containers {
+		container, ok := containerObj.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		requests, found, err := unstructured.NestedStringMap(container, "resources", "requests")
+		if err != nil || !found {
+			continue
+		}
+		gpuRequestStr, check := requests[string(gpuResource())]
+		if !check {
+			continue
+		}
+		gpuRequest, parseErr := resource.ParseQuantity(gpuRequestStr)
+		if parseErr != nil {
+			klog.Infof("Malformed launcher GPU request %q: %v", gpuRequestStr, parseErr)
+			continue
+		}
+		launcherGPUs += gpuRequest.Value()
+	}
+	return launcherGPUs
+}
+
+// mpiJobPriority resolves the job's priority from the worker template's
+// priorityClassName, so scale-in can take GPUs from low-priority jobs before
+// high-priority ones. Jobs without a class, unresolvable classes and offline
+// simulation all count as priority zero.
+func (ev *Evaluator) mpiJobPriority(ctx context.Context, MPIJob *unstructured.Unstructured) int64 {
+	className, found, err := unstructured.NestedString(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "template", "spec", "priorityClassName")
+	if err != nil || !found || className == "" || ev.Handler == nil {
+		return 0
+	}
+	priorityClass, err := ev.Handler.ClientSet().SchedulingV1().PriorityClasses().Get(ctx, className, metav1.GetOptions{})
+	if err != nil {
+		klog.Infof("Failed to resolve priority class %q: %v", className, err)
+		return 0
+	}
+	return int64(priorityClass.Value)
 }
 
 func Contains(slice []string, target string) bool {
@@ -361,129 +741,149 @@ func Contains(slice []string, target string) bool {
 }
 
 func (ev *Evaluator) idleGPUsinNodes(ctx context.Context, nodes *v1.NodeList) int64 {
+	// The allocation cache answers from informer events without walking any
+	// pods; the walk below remains the fallback until the cache has synced
+	// and for offline simulation.
+	if ev.Snapshot == nil {
+		if idle, ready := clusterIdleGPUsFromCache(); ready {
+			metrics.IdleGPUsTotal.Set(float64(idle))
+			return idle
+		}
+	}
 	capacityGPUcount := 0
 	allocatedGPUcount := 0
 	for _, node := range nodes.Items {
-		if val, ok := node.Status.Capacity["nvidia.com/gpu"]; ok {
-			capacityGPUcount += int(val.Value())
+		if ctx.Err() != nil {
+			break
+		}
+		// Unhealthy GPUs are not idle capacity.
+		if !nodeGPUsHealthy(&node) {
+			continue
 		}
-		pods, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name)})
+		nodeCapacity := nodeAcceleratorCapacity(&node)
+		capacityGPUcount += int(nodeCapacity)
+		pods, err := ev.podsOnNode(ctx, node.Name)
 		if err != nil {
 			klog.Infof("Pod loading error")
 			continue
 		}
-		for _, pod := range pods.Items {
-			for _, container := range pod.Spec.Containers {
-				if gpuRequest, ok := container.Resources.Requests["nvidia.com/gpu"]; ok {
-					allocatedGPUcount += int(gpuRequest.Value())
-				}
-			}
+		nodeAllocated := 0
+		for _, pod := range pods {
+			nodeAllocated += int(podAcceleratorRequest(pod) + ev.podDRAGPUs(ctx, pod))
 		}
+		allocatedGPUcount += nodeAllocated
+		if nodeCapacity > 0 && ev.Snapshot == nil {
+			recordIdleGPUs(node.Name, nodeCapacity-int64(nodeAllocated))
+			metrics.IdleGPUsPerNode.WithLabelValues(node.Name).Set(float64(nodeCapacity - int64(nodeAllocated)))
+		}
+	}
+	if ev.Snapshot == nil {
+		metrics.IdleGPUsTotal.Set(float64(capacityGPUcount - allocatedGPUcount))
 	}
 	return int64(capacityGPUcount - allocatedGPUcount)
 }
 
+// GetMPIJob fetches the MPIJob from the shared informer cache, or from the
+// snapshot in offline simulation. A direct API read through the builtin
+// mpijob adapter remains the fallback while the cache syncs. Mutations keep
+// going through the adapter, which re-reads with a resourceVersion
+// precondition anyway.
 func (ev *Evaluator) GetMPIJob(ctx context.Context, ns string, MPIJobName string) (*unstructured.Unstructured, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", "/etc/kubernetes/scheduler.conf")
-	if err != nil {
-		klog.Infof("Failed to get in-cluster config: %v", err)
+	if ev.Snapshot != nil {
+		if job := ev.Snapshot.elasticJob(MPIJobName); job != nil {
+			return job, nil
+		}
+		return nil, fmt.Errorf("elastic job %v not in snapshot", MPIJobName)
+	}
+	if lister := sharedMPIJobLister(); lister != nil {
+		obj, err := lister.ByNamespace(ns).Get(MPIJobName)
+		if err == nil {
+			if job, ok := obj.(*unstructured.Unstructured); ok {
+				// Callers mutate the object while planning; the cache copy
+				// must stay pristine.
+				return job.DeepCopy(), nil
+			}
+		}
+		// A cache miss falls through to the API: the job may exist but not
+		// have reached the cache yet.
 	}
-	dynamicClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		klog.Infof("Failed to create dynamic client: %v", err)
+	return ev.GetElasticJob(ctx, mpiJobAdapter, ns, MPIJobName)
+}
+
+// elasticJobNameOfPod returns the MPIJob a running pod belongs to, or ""
+// for pods outside the elastic machinery. With a selector configured, only
+// pods whose labels match count and the job name comes from the kubeflow
+// job-name label; without one, the historical *-elastic-* name pattern
+// applies.
+func elasticJobNameOfPod(pod *v1.Pod) string {
+	if dynamicArgs().ElasticJobSelector != "" {
+		selector, err := labels.Parse(dynamicArgs().ElasticJobSelector)
+		if err != nil {
+			klog.Infof("Unusable elastic job selector %q: %v", dynamicArgs().ElasticJobSelector, err)
+			return ""
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			return ""
+		}
+		if jobName, check := pod.Labels["training.kubeflow.org/job-name"]; check {
+			return jobName
+		}
+		// Matching pods without the kubeflow label fall back to the name
+		// pattern below.
+	}
+	podNameSlice := strings.Split(pod.Name, "-")
+	if len(podNameSlice) < 2 {
+		return ""
 	}
-	gvr := schema.GroupVersionResource{
-		Group:    "kubeflow.org",
-		Version:  "v1",
-		Resource: "mpijobs",
+	if podNameSlice[len(podNameSlice)-1] == "launcher" && podNameSlice[len(podNameSlice)-2] == "elastic" {
+		return strings.Join(podNameSlice[:len(podNameSlice)-1], "-")
 	}
-	MPIJob, err := dynamicClient.Resource(gvr).Namespace(ns).Get(ctx, MPIJobName, metav1.GetOptions{})
-	return MPIJob, err
+	if len(podNameSlice) >= 3 && podNameSlice[len(podNameSlice)-2] == "worker" && podNameSlice[len(podNameSlice)-3] == "elastic" {
+		return strings.Join(podNameSlice[:len(podNameSlice)-2], "-")
+	}
+	return ""
 }
 
 func (ev *Evaluator) ListRunningMPIJob(ctx context.Context, nodes *v1.NodeList) []string {
 	var ListofRunningMPIJob []string
 	for _, node := range nodes.Items {
-		pods, err := ev.Handler.ClientSet().CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name)})
+		if ctx.Err() != nil {
+			break
+		}
+		pods, err := ev.podsOnNode(ctx, node.Name)
 		if err != nil {
 			klog.Infof("Pod load error")
 			continue
 		}
-		for _, pod := range pods.Items {
-			podNameSlice := strings.Split(pod.Name, "-")
-			if len(podNameSlice) < 2 {
+		for _, pod := range pods {
+			MPIJobName := elasticJobNameOfPod(pod)
+			if MPIJobName == "" {
 				continue
 			}
-
-			if podNameSlice[len(podNameSlice)-1] == "launcher" && podNameSlice[len(podNameSlice)-2] == "elastic" {
-				MPIJobName := strings.Join(podNameSlice[:len(podNameSlice)-1], "-")
-				if !Contains(ListofRunningMPIJob, MPIJobName) {
-					ListofRunningMPIJob = append(ListofRunningMPIJob, MPIJobName)
-				}
-			} else if podNameSlice[len(podNameSlice)-2] == "worker" && podNameSlice[len(podNameSlice)-3] == "elastic" {
-				MPIJobName := strings.Join(podNameSlice[:len(podNameSlice)-2], "-")
-				if !Contains(ListofRunningMPIJob, MPIJobName) {
-					ListofRunningMPIJob = append(ListofRunningMPIJob, MPIJobName)
-				}
+			if Contains(ListofRunningMPIJob, MPIJobName) {
+				continue
+			}
+			// Under the Kueue integration, a job without an admitted
+			// Workload is Kueue's to manage and never scaled here.
+			if !ev.jobWorkloadAdmitted(ctx, dynamicArgs().Namespace, MPIJobName) {
+				continue
 			}
+			ListofRunningMPIJob = append(ListofRunningMPIJob, MPIJobName)
 		}
 	}
+	recordElasticJobs(ListofRunningMPIJob)
 	return ListofRunningMPIJob
 }
 
-func (ev *Evaluator) MPIJobScaling(ctx context.Context, ns string, MPIJobName string, scaleNum int64) {
-	config, err := clientcmd.BuildConfigFromFlags("", "/etc/kubernetes/scheduler.conf")
-	if err != nil {
-		klog.Infof("Failed to get in-cluster config: %v", err)
-	}
-	dynamicClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		klog.Infof("Failed to create dynamic client: %v", err)
+// MPIJobScaling adjusts the MPIJob worker replicas through the builtin
+// mpijob adapter. With the async executor enabled, the mutation is only
+// enqueued and the scheduling cycle moves on.
+func (ev *Evaluator) MPIJobScaling(ctx context.Context, ns string, MPIJobName string, scaleNum int64, auditAnnotations map[string]string) {
+	if dynamicArgs().AsyncScaleExecutor {
+		enqueueScaleIntent(ns, MPIJobName, scaleNum, auditAnnotations)
+		return
 	}
-	gvr := schema.GroupVersionResource{
-		Group:    "kubeflow.org",
-		Version:  "v1",
-		Resource: "mpijobs",
-	}
-	MPIJob, err := dynamicClient.Resource(gvr).Namespace(ns).Get(ctx, MPIJobName, metav1.GetOptions{})
-	if err != nil {
-		klog.Infof("Failed to list MPIJobs: %v", err)
-	}
-
-	nowGPUcount, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
-	if err != nil {
-		klog.Infof("Error reading replicas: %v", err)
-	}
-	if !found {
-		klog.Infof("Replicas not found")
-	}
-
-	workerReplicasPath := []string{"spec", "mpiReplicaSpecs", "Worker", "replicas"}
-	if err := unstructured.SetNestedField(MPIJob.Object, int64(nowGPUcount+scaleNum), workerReplicasPath...); err != nil {
-		klog.Infof("Failed to set replicas: %v", err)
-	}
-
-	if scaleNum > 0 {
-		annotations, found, err := unstructured.NestedStringMap(MPIJob.Object, "metadata", "annotations")
-		if err != nil {
-			klog.Infof("Error loading annotations: %v", err)
-			return
-		}
-		if !found {
-			annotations = make(map[string]string)
-			annotations["scale-out"] = strconv.Itoa(int(scaleNum))
-		} else {
-			annotations["scale-out"] += strconv.Itoa(int(scaleNum))
-		}
-		if err := unstructured.SetNestedStringMap(MPIJob.Object, annotations, "metadata", "annotations"); err != nil {
-			klog.Infof("Failed to set annotations: %v", err)
-		}
-	}
-	updatedMPIJob, err := dynamicClient.Resource(gvr).Namespace(ns).Update(ctx, MPIJob, metav1.UpdateOptions{})
-	if err != nil {
-		klog.Infof("Failed to update MPIJob: %v", err)
-	}
-	klog.Infof("Updated Info : %v", updatedMPIJob.Object)
+	ev.ElasticJobScaling(ctx, mpiJobAdapter, ns, MPIJobName, scaleNum, auditAnnotations)
 }
 
 // Preempt returns a PostFilterResult carrying suggested nominatedNodeName, along with a Status.
@@ -841,6 +1241,11 @@ func pickOneNodeForPreemption(logger klog.Logger, nodesToVictims map[string]*ext
 			// The smaller the length of pods, the higher the score.
 			return -int64(len(nodesToVictims[node].Pods))
 		}
+		minEvictionCostScoreFunc := func(node string) int64 {
+			// The cheaper the victims are to evict under the unified cost
+			// model, the higher the score.
+			return -int64(sumEvictionCost(nodesToVictims[node].Pods, evictionCostWeights))
+		}
 		latestStartTimeScoreFunc := func(node string) int64 {
 			// Get the earliest start time of all pods on the current node.
 			earliestStartTimeOnNode := util.GetEarliestPodStartTime(nodesToVictims[node])
@@ -866,6 +1271,8 @@ func pickOneNodeForPreemption(logger klog.Logger, nodesToVictims map[string]*ext
 			minNumPodsScoreFunc,
 			// A node with the latest start time of all highest priority victims is preferable.
 			latestStartTimeScoreFunc,
+			// A node whose victims are cheapest under the eviction cost model is preferable.
+			minEvictionCostScoreFunc,
 			// If there are still ties, then the first Node in the list is selected.
 		}
 	}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+// DynamicProfilePolicy overrides parts of the Dynamic policy for pods
+// scheduled under one scheduler profile, so e.g. a "research" profile can
+// scale jobs in aggressively while a "production" profile only retracts
+// backfilled pods. Unset fields inherit the global args.
+type DynamicProfilePolicy struct {
+	// Preset names a policy bundle the profile starts from: "aggressive",
+	// "conservative" or "retract-only". Explicit fields below override the
+	// preset; empty starts from the zero policy.
+	Preset string `json:"preset,omitempty"`
+	// ScaleOutPolicy overrides the global scale-out selection policy.
+	ScaleOutPolicy string `json:"scaleOutPolicy,omitempty"`
+	// CostAwareScaleOut overrides the global cost-awareness switch.
+	CostAwareScaleOut *bool `json:"costAwareScaleOut,omitempty"`
+	// RetractOnly limits the profile to retracting backfilled pods: elastic
+	// jobs are neither shrunk nor grown for its pods.
+	RetractOnly bool `json:"retractOnly,omitempty"`
+}
+
+// The presets a profile may start from. Aggressive grows whichever job
+// models the most throughput regardless of node cost; conservative picks
+// jobs dominant-resource-fairly and weighs node cost; retract-only never
+// mutates elastic jobs at all.
+const (
+	ProfilePresetAggressive   = "aggressive"
+	ProfilePresetConservative = "conservative"
+	ProfilePresetRetractOnly  = "retract-only"
+)
+
+// presetPolicy expands a preset name into the policy it stands for.
+func presetPolicy(preset string) DynamicProfilePolicy {
+	costAware := preset == ProfilePresetConservative
+	switch preset {
+	case ProfilePresetAggressive:
+		return DynamicProfilePolicy{ScaleOutPolicy: ScaleOutPolicyMaxThroughput, CostAwareScaleOut: &costAware}
+	case ProfilePresetConservative:
+		return DynamicProfilePolicy{ScaleOutPolicy: ScaleOutPolicyDRF, CostAwareScaleOut: &costAware}
+	case ProfilePresetRetractOnly:
+		return DynamicProfilePolicy{RetractOnly: true}
+	}
+	return DynamicProfilePolicy{}
+}
+
+// profilePresetKnown reports whether a preset name is valid; the empty
+// string means no preset.
+func profilePresetKnown(preset string) bool {
+	switch preset {
+	case "", ProfilePresetAggressive, ProfilePresetConservative, ProfilePresetRetractOnly:
+		return true
+	}
+	return false
+}
+
+// profilePolicy returns the overrides for a scheduler profile; the zero
+// value inherits everything. A configured preset is expanded first and
+// explicit fields win over it.
+func profilePolicy(profile string) DynamicProfilePolicy {
+	policy := dynamicArgs().ProfilePolicies[profile]
+	if policy.Preset == "" {
+		return policy
+	}
+	merged := presetPolicy(policy.Preset)
+	if policy.ScaleOutPolicy != "" {
+		merged.ScaleOutPolicy = policy.ScaleOutPolicy
+	}
+	if policy.CostAwareScaleOut != nil {
+		merged.CostAwareScaleOut = policy.CostAwareScaleOut
+	}
+	if policy.RetractOnly {
+		merged.RetractOnly = true
+	}
+	return merged
+}
+
+// profileName resolves the scheduler profile the Evaluator serves. The
+// framework implementation behind Handle carries the profile name; handles
+// that do not (e.g. test fixtures) map to the empty profile.
+func (ev *Evaluator) profileName() string {
+	if named, ok := ev.Handler.(interface{ ProfileName() string }); ok {
+		return named.ProfileName()
+	}
+	return ""
+}
+
+// scaleOutPolicyFor returns the selection policy effective for the profile.
+func scaleOutPolicyFor(profile string) string {
+	if policy := profilePolicy(profile).ScaleOutPolicy; policy != "" {
+		return policy
+	}
+	return dynamicArgs().ScaleOutPolicy
+}
+
+// costAwareScaleOutFor returns the cost-awareness switch effective for the
+// profile.
+func costAwareScaleOutFor(profile string) bool {
+	if override := profilePolicy(profile).CostAwareScaleOut; override != nil {
+		return *override
+	}
+	return dynamicArgs().CostAwareScaleOut
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// ProtectedNamespaceLabel, set to "true" on a namespace, exempts every pod
+// and elastic job in it from retraction and scale-in. Protection is the
+// namespace owner's call, so it lives on the Namespace object instead of
+// per-pod annotations.
+const ProtectedNamespaceLabel = "scheduling.k8s.io/protected"
+
+// protectedNamespaceTTL bounds how stale a cached protection verdict may
+// get; label flips are picked up within this window.
+const protectedNamespaceTTL = time.Minute
+
+type protectedNamespaceEntry struct {
+	protected bool
+	fetched   time.Time
+}
+
+var (
+	protectedNamespaceLock  sync.Mutex
+	protectedNamespaceCache = map[string]protectedNamespaceEntry{}
+)
+
+// namespaceProtected reports whether the namespace carries
+// ProtectedNamespaceLabel=true. Verdicts are cached briefly so planning
+// does not re-read the same Namespace object per victim. Lookup failures
+// are logged and treated as unprotected; a missing namespace cannot be
+// holding workloads worth protecting for long.
+func (ev *Evaluator) namespaceProtected(ctx context.Context, namespace string) bool {
+	if ev.Snapshot != nil {
+		return false
+	}
+	protectedNamespaceLock.Lock()
+	entry, cached := protectedNamespaceCache[namespace]
+	protectedNamespaceLock.Unlock()
+	if cached && time.Since(entry.fetched) < protectedNamespaceTTL {
+		return entry.protected
+	}
+	protected := false
+	ns, err := ev.Handler.ClientSet().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		klog.Infof("Failed to check protection of namespace %v: %v", namespace, err)
+	} else if value, check := ns.Labels[ProtectedNamespaceLabel]; check {
+		protected, _ = strconv.ParseBool(value)
+	}
+	protectedNamespaceLock.Lock()
+	protectedNamespaceCache[namespace] = protectedNamespaceEntry{protected: protected, fetched: time.Now()}
+	protectedNamespaceLock.Unlock()
+	return protected
+}
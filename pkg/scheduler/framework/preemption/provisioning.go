@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+// provisioningRequestGVR locates the cluster autoscaler's namespaced
+// ProvisioningRequest resource. When retraction, scale-in and scale-out all
+// fail to cover a pending pod, Dynamic can hand the shortage to the
+// autoscaler instead of leaving the pod to starve on a full cluster.
+var provisioningRequestGVR = schema.GroupVersionResource{
+	Group:    "autoscaling.x-k8s.io",
+	Version:  "v1beta1",
+	Resource: "provisioningrequests",
+}
+
+// requestGPUProvisioning asks the cluster autoscaler for capacity covering
+// the pending pod, by creating a ProvisioningRequest whose pod template
+// carries the pod's GPU demand. One request is kept per pod; if it already
+// exists the autoscaler is on it and the pod just waits for the node-add
+// requeue hint. Like the audit trail this is best effort: a missing CRD or
+// a write error is logged and the pod stays plainly unschedulable.
+func (ev *Evaluator) requestGPUProvisioning(ctx context.Context, pod *v1.Pod, requestGPUs int64) {
+	if ev.Snapshot != nil || dynamicArgs().ProvisioningClassName == "" {
+		return
+	}
+	dynamicClient, err := elasticJobClient()
+	if err != nil {
+		klog.Infof("Failed to create dynamic client for provisioning: %v", err)
+		return
+	}
+	name := pod.Name + "-gpu-provisioning"
+	if _, err := dynamicClient.Resource(provisioningRequestGVR).Namespace(pod.Namespace).Get(ctx, name, metav1.GetOptions{}); err == nil {
+		return
+	}
+	// The autoscaler simulates the referenced template, so it must see the
+	// same GPU requests the scheduler failed to place.
+	template := &v1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: pod.Namespace},
+		Template:   v1.PodTemplateSpec{Spec: *pod.Spec.DeepCopy()},
+	}
+	if _, err := ev.Handler.ClientSet().CoreV1().PodTemplates(pod.Namespace).Create(ctx, template, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		klog.Infof("Failed to create pod template for provisioning: %v", err)
+		return
+	}
+	request := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": provisioningRequestGVR.Group + "/" + provisioningRequestGVR.Version,
+		"kind":       "ProvisioningRequest",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": pod.Namespace,
+		},
+		"spec": map[string]interface{}{
+			"provisioningClassName": dynamicArgs().ProvisioningClassName,
+			"podSets": []interface{}{
+				map[string]interface{}{
+					"podTemplateRef": map[string]interface{}{"name": name},
+					"count":          int64(1),
+				},
+			},
+		},
+	}}
+	if _, err := dynamicClient.Resource(provisioningRequestGVR).Namespace(pod.Namespace).Create(ctx, request, metav1.CreateOptions{}); err != nil {
+		klog.Infof("Failed to create ProvisioningRequest for %v: %v", pod.Name, err)
+		return
+	}
+	if ev.Handler != nil {
+		ev.Handler.EventRecorder().Eventf(pod, nil, v1.EventTypeNormal, "GPUProvisioningRequested", "ElasticPlanning", "requested %d GPU(s) from the cluster autoscaler; pod requeues when nodes are added", requestGPUs)
+	}
+	klog.Infof("Created ProvisioningRequest %v for %d GPU(s)", name, requestGPUs)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Annotation fallbacks for jobs whose CRD carries no elastic policy.
+const (
+	minReplicasAnnotation = "scheduler.alpha/min-replicas"
+	maxReplicasAnnotation = "scheduler.alpha/max-replicas"
+)
+
+// workerBounds returns the worker count range the job itself declares, read
+// through the adapter's bounds paths so each workload kind's own fields are
+// honored. The paths win, then the annotations. The global MaxWorkerReplicas
+// still caps the maximum, and the co-scheduling floor (minMember minus the
+// launcher) raises the minimum, so a job cannot opt out of either through
+// its own spec.
+func (adapter ElasticJobAdapter) workerBounds(job *unstructured.Unstructured) (minWorkers, maxWorkers int64) {
+	minWorkers = 1
+	maxWorkers = dynamicArgs().MaxWorkerReplicas
+	annotations := job.GetAnnotations()
+
+	if v, found := nestedInt64Path(job.Object, adapter.MinReplicasPath); found && v > 0 {
+		minWorkers = v
+	} else if raw, check := annotations[minReplicasAnnotation]; check {
+		if v, convErr := strconv.ParseInt(raw, 10, 64); convErr == nil && v > 0 {
+			minWorkers = v
+		}
+	}
+	if v, found := nestedInt64Path(job.Object, adapter.MaxReplicasPath); found && v > 0 {
+		maxWorkers = v
+	} else if raw, check := annotations[maxReplicasAnnotation]; check {
+		if v, convErr := strconv.ParseInt(raw, 10, 64); convErr == nil && v > 0 {
+			maxWorkers = v
+		}
+	}
+
+	if floor := mpiJobMinMember(job) - 1; floor > minWorkers {
+		minWorkers = floor
+	}
+	if maxWorkers > dynamicArgs().MaxWorkerReplicas {
+		maxWorkers = dynamicArgs().MaxWorkerReplicas
+	}
+	if maxWorkers < minWorkers {
+		maxWorkers = minWorkers
+	}
+	return minWorkers, maxWorkers
+}
+
+// mpiJobWorkerBounds is the MPIJob-path callers' shorthand for workerBounds.
+func mpiJobWorkerBounds(job *unstructured.Unstructured) (minWorkers, maxWorkers int64) {
+	return mpiJobAdapter.workerBounds(job)
+}
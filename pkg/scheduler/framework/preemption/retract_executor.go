@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// ActionStatus is the execution state of one retrieve-plan action.
+type ActionStatus string
+
+const (
+	// ActionPending means the action has not been executed (yet, or because
+	// execution was cancelled before reaching it).
+	ActionPending ActionStatus = "pending"
+	// ActionSucceeded means the mutation went through.
+	ActionSucceeded ActionStatus = "succeeded"
+	// ActionFailed means the mutation errored; execution stops and already
+	// executed scale-ins are rolled back.
+	ActionFailed ActionStatus = "failed"
+	// ActionRolledBack means the scale-in went through but was reverted
+	// because a later action failed.
+	ActionRolledBack ActionStatus = "rolled-back"
+)
+
+// ActionProgress pairs one plan action with its execution outcome.
+type ActionProgress struct {
+	// Action is the planned retraction or scale-in.
+	Action RetrieveAction
+	// Status is where the action stands.
+	Status ActionStatus
+	// Message holds the failure reason when Status is ActionFailed.
+	Message string
+}
+
+// RetractExecutor executes an already-computed RetrievePlan, keeping
+// planning (PlanRetrieve) and acting strictly separate. It tracks per-action
+// progress, honors context cancellation between actions, and writes each
+// outcome to the decision log, so async execution, approval flows, and
+// rollback can build on it.
+type RetractExecutor struct {
+	ev        *Evaluator
+	plan      *RetrievePlan
+	planUID   string
+	preemptor *v1.Pod
+
+	lock     sync.Mutex
+	progress []ActionProgress
+}
+
+// NewRetractExecutor prepares an executor for <plan>; every action starts
+// out pending.
+func NewRetractExecutor(ev *Evaluator, plan *RetrievePlan, planUID string, preemptor *v1.Pod) *RetractExecutor {
+	progress := make([]ActionProgress, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		progress = append(progress, ActionProgress{Action: action, Status: ActionPending})
+	}
+	return &RetractExecutor{
+		ev:        ev,
+		plan:      plan,
+		planUID:   planUID,
+		preemptor: preemptor,
+		progress:  progress,
+	}
+}
+
+// Progress returns a snapshot of the per-action execution state.
+func (ex *RetractExecutor) Progress() []ActionProgress {
+	ex.lock.Lock()
+	defer ex.lock.Unlock()
+	snapshot := make([]ActionProgress, len(ex.progress))
+	copy(snapshot, ex.progress)
+	return snapshot
+}
+
+// setStatus records the outcome of action <i> and logs it against the plan.
+func (ex *RetractExecutor) setStatus(i int, status ActionStatus, message string) {
+	ex.lock.Lock()
+	ex.progress[i].Status = status
+	ex.progress[i].Message = message
+	action := ex.progress[i].Action
+	ex.lock.Unlock()
+	if action.Retract {
+		klog.Infof("Plan %v action %d: retract %v %v (%v)", ex.planUID, i, action.VictimPod.Name, status, message)
+	} else {
+		klog.Infof("Plan %v action %d: scale-in %v by %d %v (%v)", ex.planUID, i, action.MPIJobName, action.GPUsFreed, status, message)
+	}
+}
+
+// verifyPlan rechecks every action against the live cluster before the
+// first destructive mutation: victims must still exist as backfilled pods
+// (or be parked at Permit) and scale-in targets must still have the workers
+// to give. Planning ran against a snapshot; a stale plan that can no longer
+// free enough GPUs is dropped whole instead of executed halfway.
+func (ex *RetractExecutor) verifyPlan(ctx context.Context) bool {
+	for _, action := range ex.plan.Actions {
+		if action.Retract {
+			if ex.ev.Handler.GetWaitingPod(action.VictimPod.UID) != nil {
+				continue
+			}
+			victim, err := ex.ev.Handler.ClientSet().CoreV1().Pods(action.VictimPod.Namespace).Get(ctx, action.VictimPod.Name, metav1.GetOptions{})
+			if err != nil {
+				klog.Infof("Plan %v stale: victim %v unavailable: %v", ex.planUID, action.VictimPod.Name, err)
+				return false
+			}
+			if schedStateOfPod, check := victim.Annotations[util.SchedulingStateAnnotation]; !check || schedStateOfPod != "backfilled" {
+				klog.Infof("Plan %v stale: victim %v is no longer backfilled", ex.planUID, victim.Name)
+				return false
+			}
+			continue
+		}
+		MPIJob, err := ex.ev.GetMPIJob(ctx, dynamicArgs().Namespace, action.MPIJobName)
+		if err != nil {
+			klog.Infof("Plan %v stale: MPIJob %v unavailable: %v", ex.planUID, action.MPIJobName, err)
+			return false
+		}
+		replicas, found, err := unstructured.NestedInt64(MPIJob.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+		if err != nil || !found {
+			klog.Infof("Plan %v stale: MPIJob %v has no readable replicas", ex.planUID, action.MPIJobName)
+			return false
+		}
+		minWorkers, _ := mpiJobWorkerBounds(MPIJob)
+		if replicas-int64(action.GPUsFreed) < minWorkers {
+			klog.Infof("Plan %v stale: MPIJob %v shrank to %d workers and can no longer free %d", ex.planUID, action.MPIJobName, replicas, action.GPUsFreed)
+			return false
+		}
+	}
+	return true
+}
+
+// rollback reverts the scale-ins executed before action <failed>, newest
+// first, so the jobs get their workers back when the plan cannot deliver
+// the GPUs it promised. Retractions are not reversible; their victims are
+// recreated by their controllers if they are managed.
+func (ex *RetractExecutor) rollback(ctx context.Context, failed int) {
+	for i := failed - 1; i >= 0; i-- {
+		progress := ex.Progress()[i]
+		if progress.Action.Retract || progress.Status != ActionSucceeded {
+			continue
+		}
+		ex.ev.MPIJobScaling(ctx, dynamicArgs().Namespace, progress.Action.MPIJobName, int64(progress.Action.GPUsFreed), scaleAuditAnnotations(ex.planUID, ex.preemptor, "rollback"))
+		ex.setStatus(i, ActionRolledBack, "later action failed")
+	}
+}
+
+// Execute verifies the whole plan is still actionable, then runs its
+// actions in order. Cancelling ctx stops execution before the next action;
+// actions not reached stay pending. A failed action aborts the plan and
+// rolls back the scale-ins executed before it. It returns true when every
+// action was attempted and succeeded.
+func (ex *RetractExecutor) Execute(ctx context.Context) bool {
+	if !ex.verifyPlan(ctx) {
+		return false
+	}
+	for i, action := range ex.plan.Actions {
+		if ctx.Err() != nil {
+			klog.Infof("Plan %v cancelled after %d of %d actions", ex.planUID, i, len(ex.plan.Actions))
+			return false
+		}
+		if action.Retract {
+			// A victim still parked at Permit has not bound; rejecting it in
+			// memory frees the reservation without an API deletion.
+			if waitingPod := ex.ev.Handler.GetWaitingPod(action.VictimPod.UID); waitingPod != nil {
+				waitingPod.Reject(ex.ev.PluginName, "retracted for a higher-priority pod")
+				ex.setStatus(i, ActionSucceeded, "rejected at Permit")
+				continue
+			}
+			// A Kueue-managed victim is reclaimed through its Workload, so
+			// Kueue requeues it instead of finding a pod deleted under it.
+			if ex.ev.evictVictimViaKueue(ctx, action.VictimPod) {
+				ForgetPlacement(action.VictimPod.Namespace, action.VictimPod.Name)
+				RecordPendingRetraction(action.VictimPod)
+				ex.setStatus(i, ActionSucceeded, "evicted via Kueue Workload")
+				continue
+			}
+			// The in-memory reject above is free; only API deletions draw
+			// from the mutation buckets.
+			if !allowMutation(action.VictimPod.Namespace + "/" + action.VictimPod.Name) {
+				ex.setStatus(i, ActionFailed, "mutation rate limit exceeded")
+				ex.rollback(ctx, i)
+				return false
+			}
+			var err error
+			if action.ForceDelete {
+				// The victim sits on a fenced NotReady node; graceful
+				// retraction cannot complete there.
+				err = util.ForceDeletePod(ctx, ex.ev.Handler.ClientSet(), action.VictimPod)
+			} else {
+				err = util.RetractPod(ctx, ex.ev.Handler.ClientSet(), action.VictimPod, scaleAuditAnnotations(ex.planUID, ex.preemptor, "retract"))
+			}
+			if err != nil {
+				ex.setStatus(i, ActionFailed, err.Error())
+				ex.rollback(ctx, i)
+				return false
+			}
+			ForgetPlacement(action.VictimPod.Namespace, action.VictimPod.Name)
+			RecordPendingRetraction(action.VictimPod)
+			// A controller-owned victim is recreated right away and races the
+			// preemptor back onto the node it just freed. A nominator entry
+			// reserves the freed GPUs for the preemptor during that window.
+			if podControllerWillRecreate(action.VictimPod) && action.VictimPod.Spec.NodeName != "" {
+				ex.ev.nominateOnFreedNode(ctx, ex.preemptor, action.VictimPod.Spec.NodeName)
+			}
+		} else {
+			ex.ev.MPIJobScaling(ctx, dynamicArgs().Namespace, action.MPIJobName, -int64(action.GPUsFreed), scaleAuditAnnotations(ex.planUID, ex.preemptor, "scale-in"))
+		}
+		ex.setStatus(i, ActionSucceeded, "")
+	}
+	return true
+}
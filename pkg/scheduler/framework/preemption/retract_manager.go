@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+const retractManagerInterval = time.Minute
+
+// RunRetractManager periodically reverts scheduler-added replicas once the
+// pod that motivated the scale-out no longer needs it, until ctx is done. A
+// scale-out is a one-shot decision made while the preemptor was pending;
+// without this loop the extra workers would stay until the job's budget ran
+// out even after the preemptor scheduled elsewhere or was deleted.
+func (ev *Evaluator) RunRetractManager(ctx context.Context) {
+	wait.UntilWithContext(ctx, ev.retractManagerSweep, retractManagerInterval)
+}
+
+func (ev *Evaluator) retractManagerSweep(ctx context.Context) {
+	if !ElasticJobsAvailable() {
+		return
+	}
+	// Jobs in a protected namespace are exempt from scale-in, including
+	// reverts of earlier scheduler-added workers.
+	if ev.namespaceProtected(ctx, dynamicArgs().Namespace) {
+		return
+	}
+	nodes, err := ev.Handler.ClientSet().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Infof("Node info error in retractManagerSweep")
+		return
+	}
+	for _, MPIJobName := range ev.ListRunningMPIJob(ctx, nodes) {
+		MPIJob, err := ev.GetMPIJob(ctx, dynamicArgs().Namespace, MPIJobName)
+		if err != nil {
+			klog.Infof("Failed to get MPIJob: %v", err)
+			continue
+		}
+		annotations := MPIJob.GetAnnotations()
+		net := netScaleOut(annotations[util.ScaleOutAnnotation])
+		if net <= 0 {
+			continue
+		}
+		if !ev.scaleOutMotivationGone(ctx, annotations["audit.scheduling.k8s.io/preemptor"]) {
+			continue
+		}
+		klog.Infof("Reverting %v scheduler-added workers of %v: motivating pod no longer pending", net, MPIJobName)
+		ev.MPIJobScaling(ctx, dynamicArgs().Namespace, MPIJobName, -net, map[string]string{
+			"audit.scheduling.k8s.io/reason": "retract-manager",
+		})
+	}
+}
+
+// scaleOutMotivationGone reports whether the recorded preemptor has stopped
+// needing the scale-out: it was deleted, finished, or got scheduled. An
+// unreadable reference keeps the scale-out in place; shrinking a job on a
+// guess is worse than carrying an extra worker.
+func (ev *Evaluator) scaleOutMotivationGone(ctx context.Context, preemptorRef string) bool {
+	parts := strings.SplitN(preemptorRef, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return false
+	}
+	pod, err := ev.Handler.ClientSet().CoreV1().Pods(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true
+		}
+		klog.Infof("Failed to get preemptor %v: %v", preemptorRef, err)
+		return false
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return true
+	}
+	return pod.Spec.NodeName != ""
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+// maxOptimalCandidates bounds the knapsack solver; larger candidate sets fall
+// back to the greedy ordering to keep PostFilter latency predictable.
+const maxOptimalCandidates = 32
+
+// selectMinimalRetractionSet solves a small min-cost covering problem over
+// the retrieve candidate rows: pick the subset that frees at least needGPUs
+// with minimal summed cost (column 5), instead of greedily retracting down
+// the sorted list which can over-retract. It returns nil when the instance is
+// too large or infeasible, in which case the caller keeps the greedy path.
+func selectMinimalRetractionSet(candidates [][]int, needGPUs int) [][]int {
+	if needGPUs <= 0 || len(candidates) == 0 || len(candidates) > maxOptimalCandidates {
+		return nil
+	}
+	const unreachable = int(^uint(0) >> 1)
+	type cell struct {
+		cost  int
+		picks []int
+	}
+	// dp[g] is the cheapest subset freeing at least g GPUs (g saturated at
+	// needGPUs).
+	dp := make([]cell, needGPUs+1)
+	for g := 1; g <= needGPUs; g++ {
+		dp[g].cost = unreachable
+	}
+	for idx, candidate := range candidates {
+		gpus := candidate[2]
+		if gpus <= 0 {
+			continue
+		}
+		cost := candidate[5]
+		if cost < 0 {
+			cost = 0
+		}
+		for g := needGPUs; g >= 0; g-- {
+			if dp[g].cost == unreachable {
+				continue
+			}
+			target := g + gpus
+			if target > needGPUs {
+				target = needGPUs
+			}
+			newCost := dp[g].cost + cost
+			if newCost < dp[target].cost {
+				picks := make([]int, len(dp[g].picks), len(dp[g].picks)+1)
+				copy(picks, dp[g].picks)
+				dp[target] = cell{cost: newCost, picks: append(picks, idx)}
+			}
+		}
+	}
+	if dp[needGPUs].cost == unreachable {
+		return nil
+	}
+	result := make([][]int, 0, len(dp[needGPUs].picks))
+	for _, idx := range dp[needGPUs].picks {
+		result = append(result, candidates[idx])
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// retractionRow builds a candidate row with only the columns the solver
+// reads: GPUs freed (column 2) and eviction cost (column 5).
+func retractionRow(gpus, cost int) []int {
+	return []int{0, 0, gpus, 0, 0, cost}
+}
+
+func TestSelectMinimalRetractionSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates [][]int
+		needGPUs   int
+		want       [][]int
+	}{
+		{
+			name: "cheaper pair beats a single expensive candidate",
+			candidates: [][]int{
+				retractionRow(4, 10),
+				retractionRow(2, 2),
+				retractionRow(2, 3),
+			},
+			needGPUs: 4,
+			want: [][]int{
+				retractionRow(2, 2),
+				retractionRow(2, 3),
+			},
+		},
+		{
+			name: "over-freeing is allowed when it is cheapest",
+			candidates: [][]int{
+				retractionRow(5, 1),
+				retractionRow(2, 2),
+				retractionRow(2, 2),
+			},
+			needGPUs: 4,
+			want: [][]int{
+				retractionRow(5, 1),
+			},
+		},
+		{
+			name: "negative costs are clamped to zero",
+			candidates: [][]int{
+				retractionRow(2, -5),
+				retractionRow(2, 1),
+			},
+			needGPUs: 2,
+			want: [][]int{
+				retractionRow(2, -5),
+			},
+		},
+		{
+			name: "candidates freeing nothing are skipped",
+			candidates: [][]int{
+				retractionRow(0, 0),
+				retractionRow(3, 4),
+			},
+			needGPUs: 3,
+			want: [][]int{
+				retractionRow(3, 4),
+			},
+		},
+		{
+			name: "infeasible demand keeps the greedy path",
+			candidates: [][]int{
+				retractionRow(1, 1),
+				retractionRow(1, 1),
+			},
+			needGPUs: 3,
+			want:     nil,
+		},
+		{
+			name:       "non-positive demand keeps the greedy path",
+			candidates: [][]int{retractionRow(2, 1)},
+			needGPUs:   0,
+			want:       nil,
+		},
+		{
+			name:       "no candidates keeps the greedy path",
+			candidates: nil,
+			needGPUs:   2,
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectMinimalRetractionSet(tt.candidates, tt.needGPUs)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("selectMinimalRetractionSet() unexpected result (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestSelectMinimalRetractionSetTooLarge checks the solver declines oversized
+// instances instead of burning PostFilter latency on them.
+func TestSelectMinimalRetractionSetTooLarge(t *testing.T) {
+	candidates := make([][]int, maxOptimalCandidates+1)
+	for i := range candidates {
+		candidates[i] = retractionRow(1, 1)
+	}
+	if got := selectMinimalRetractionSet(candidates, 2); got != nil {
+		t.Errorf("selectMinimalRetractionSet() = %v, want nil for %d candidates", got, len(candidates))
+	}
+}
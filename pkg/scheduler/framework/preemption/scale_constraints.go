@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// Annotations a job may carry to constrain scheduler-driven scaling. Some
+// frameworks only support particular worker counts or can only grow without
+// a restart.
+const (
+	// scaleStepAnnotation makes replica deltas multiples of this step.
+	scaleStepAnnotation = "scale-step"
+	// scaleAllowedReplicasAnnotation is a comma-separated list of the only
+	// worker counts the framework supports, e.g. "1,2,4,8".
+	scaleAllowedReplicasAnnotation = "scale-allowed-replicas"
+	// scaleShrinkableAnnotation set to "false" forbids scale-in entirely.
+	scaleShrinkableAnnotation = "scale-shrinkable"
+)
+
+// scaleConstraints is the parsed form of the scaling-constraint annotations.
+type scaleConstraints struct {
+	step            int64
+	allowedReplicas []int64
+	shrinkable      bool
+}
+
+// parseScaleConstraints reads the constraint annotations; absent or
+// malformed values fall back to unconstrained scaling.
+func parseScaleConstraints(annotations map[string]string) scaleConstraints {
+	constraints := scaleConstraints{step: 1, shrinkable: true}
+	if stepStr, check := annotations[scaleStepAnnotation]; check {
+		if step, err := strconv.ParseInt(stepStr, 10, 64); err == nil && step > 0 {
+			constraints.step = step
+		} else {
+			klog.Infof("Ignoring malformed %v: %q", scaleStepAnnotation, stepStr)
+		}
+	}
+	if allowedStr, check := annotations[scaleAllowedReplicasAnnotation]; check {
+		for _, countStr := range strings.Split(allowedStr, ",") {
+			count, err := strconv.ParseInt(strings.TrimSpace(countStr), 10, 64)
+			if err != nil {
+				klog.Infof("Ignoring malformed %v: %q", scaleAllowedReplicasAnnotation, allowedStr)
+				constraints.allowedReplicas = nil
+				break
+			}
+			constraints.allowedReplicas = append(constraints.allowedReplicas, count)
+		}
+	}
+	if shrinkableStr, check := annotations[scaleShrinkableAnnotation]; check {
+		if shrinkable, err := strconv.ParseBool(shrinkableStr); err == nil {
+			constraints.shrinkable = shrinkable
+		} else {
+			klog.Infof("Ignoring malformed %v: %q", scaleShrinkableAnnotation, shrinkableStr)
+		}
+	}
+	return constraints
+}
+
+// allows reports whether the framework supports running <replicas> workers.
+func (constraints scaleConstraints) allows(replicas int64) bool {
+	if len(constraints.allowedReplicas) == 0 {
+		return true
+	}
+	for _, allowed := range constraints.allowedReplicas {
+		if allowed == replicas {
+			return true
+		}
+	}
+	return false
+}
+
+// nextGrowDelta returns the smallest allowed scale-out delta from <current>
+// workers that stays at or below <max>, or 0 when no further growth is
+// allowed.
+func (constraints scaleConstraints) nextGrowDelta(current, max int64) int64 {
+	for delta := constraints.step; current+delta <= max; delta += constraints.step {
+		if constraints.allows(current + delta) {
+			return delta
+		}
+	}
+	return 0
+}
+
+// clampShrink returns the largest allowed scale-in delta from <current>
+// workers that does not exceed <desired>, or 0 when the job cannot shrink
+// that way.
+func (constraints scaleConstraints) clampShrink(current, desired int64) int64 {
+	if !constraints.shrinkable {
+		return 0
+	}
+	for delta := desired - desired%constraints.step; delta > 0; delta -= constraints.step {
+		if constraints.allows(current - delta) {
+			return delta
+		}
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScaleConstraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        scaleConstraints
+	}{
+		{
+			name:        "absent annotations leave scaling unconstrained",
+			annotations: nil,
+			want:        scaleConstraints{step: 1, shrinkable: true},
+		},
+		{
+			name: "all annotations parsed",
+			annotations: map[string]string{
+				scaleStepAnnotation:            "2",
+				scaleAllowedReplicasAnnotation: "1, 2,4,8",
+				scaleShrinkableAnnotation:      "false",
+			},
+			want: scaleConstraints{step: 2, allowedReplicas: []int64{1, 2, 4, 8}, shrinkable: false},
+		},
+		{
+			name:        "malformed step falls back to one",
+			annotations: map[string]string{scaleStepAnnotation: "two"},
+			want:        scaleConstraints{step: 1, shrinkable: true},
+		},
+		{
+			name:        "non-positive step falls back to one",
+			annotations: map[string]string{scaleStepAnnotation: "0"},
+			want:        scaleConstraints{step: 1, shrinkable: true},
+		},
+		{
+			name:        "one malformed entry drops the whole allowed list",
+			annotations: map[string]string{scaleAllowedReplicasAnnotation: "1,2,x,8"},
+			want:        scaleConstraints{step: 1, shrinkable: true},
+		},
+		{
+			name:        "malformed shrinkable keeps the job shrinkable",
+			annotations: map[string]string{scaleShrinkableAnnotation: "nope"},
+			want:        scaleConstraints{step: 1, shrinkable: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseScaleConstraints(tt.annotations)
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(scaleConstraints{})); diff != "" {
+				t.Errorf("parseScaleConstraints() unexpected result (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScaleConstraintsNextGrowDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints scaleConstraints
+		current     int64
+		max         int64
+		want        int64
+	}{
+		{
+			name:        "unconstrained grows by one",
+			constraints: scaleConstraints{step: 1, shrinkable: true},
+			current:     3,
+			max:         5,
+			want:        1,
+		},
+		{
+			name:        "step constrains the delta",
+			constraints: scaleConstraints{step: 2, shrinkable: true},
+			current:     2,
+			max:         8,
+			want:        2,
+		},
+		{
+			name:        "allowed list skips unsupported counts",
+			constraints: scaleConstraints{step: 1, allowedReplicas: []int64{1, 2, 4, 8}, shrinkable: true},
+			current:     2,
+			max:         8,
+			want:        2,
+		},
+		{
+			name:        "next allowed count above max means no growth",
+			constraints: scaleConstraints{step: 1, allowedReplicas: []int64{1, 2, 4, 8}, shrinkable: true},
+			current:     4,
+			max:         6,
+			want:        0,
+		},
+		{
+			name:        "already at max means no growth",
+			constraints: scaleConstraints{step: 1, shrinkable: true},
+			current:     5,
+			max:         5,
+			want:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.constraints.nextGrowDelta(tt.current, tt.max); got != tt.want {
+				t.Errorf("nextGrowDelta(%v, %v) = %v, want %v", tt.current, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleConstraintsClampShrink(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints scaleConstraints
+		current     int64
+		desired     int64
+		want        int64
+	}{
+		{
+			name:        "non-shrinkable jobs never shrink",
+			constraints: scaleConstraints{step: 1, shrinkable: false},
+			current:     5,
+			desired:     3,
+			want:        0,
+		},
+		{
+			name:        "unconstrained shrinks by the desired delta",
+			constraints: scaleConstraints{step: 1, shrinkable: true},
+			current:     5,
+			desired:     3,
+			want:        3,
+		},
+		{
+			name:        "step rounds the delta down",
+			constraints: scaleConstraints{step: 2, shrinkable: true},
+			current:     6,
+			desired:     3,
+			want:        2,
+		},
+		{
+			name:        "allowed list caps the shrink",
+			constraints: scaleConstraints{step: 1, allowedReplicas: []int64{1, 2, 4}, shrinkable: true},
+			current:     4,
+			desired:     3,
+			want:        3,
+		},
+		{
+			name:        "no allowed count reachable means no shrink",
+			constraints: scaleConstraints{step: 1, allowedReplicas: []int64{4, 8}, shrinkable: true},
+			current:     8,
+			desired:     3,
+			want:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.constraints.clampShrink(tt.current, tt.desired); got != tt.want {
+				t.Errorf("clampShrink(%v, %v) = %v, want %v", tt.current, tt.desired, got, tt.want)
+			}
+		})
+	}
+}
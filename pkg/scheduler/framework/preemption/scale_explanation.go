@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// scaleExplanationAnnotation carries a structured explanation of the last
+// scheduler-driven replica change, written onto the job next to the audit
+// annotations, so owners can answer "why did my replica count change" from
+// the job itself instead of the scheduler's logs.
+const scaleExplanationAnnotation = "scheduler.alpha/scale-explanation"
+
+// scaleAlternative is one candidate that competed with the chosen job.
+type scaleAlternative struct {
+	Job                string  `json:"job"`
+	Delta              int64   `json:"delta"`
+	MarginalThroughput float64 `json:"marginalThroughput"`
+}
+
+// scaleExplanation is the payload behind scaleExplanationAnnotation.
+type scaleExplanation struct {
+	// Trigger is the pending pod the change was made for.
+	Trigger string `json:"trigger"`
+	// Delta is the worker change applied to this job.
+	Delta int64 `json:"delta"`
+	// MarginalThroughput is the modeled gain that won the selection.
+	MarginalThroughput float64 `json:"marginalThroughput"`
+	// Alternatives are the other eligible jobs and why they ranked lower.
+	Alternatives []scaleAlternative `json:"alternatives,omitempty"`
+}
+
+// scaleOutExplanation renders the explanation for growing <chosen> among
+// <candidates>, as a JSON annotation value. A marshalling failure returns
+// the empty string and the annotation is simply not written.
+func scaleOutExplanation(trigger *v1.Pod, chosen *scaleOutCandidate, candidates []scaleOutCandidate) string {
+	explanation := scaleExplanation{
+		Trigger:            trigger.Namespace + "/" + trigger.Name,
+		Delta:              chosen.delta,
+		MarginalThroughput: chosen.marginal,
+	}
+	for _, candidate := range candidates {
+		if candidate.name == chosen.name {
+			continue
+		}
+		explanation.Alternatives = append(explanation.Alternatives, scaleAlternative{
+			Job:                candidate.name,
+			Delta:              candidate.delta,
+			MarginalThroughput: candidate.marginal,
+		})
+	}
+	data, err := json.Marshal(explanation)
+	if err != nil {
+		klog.Infof("Failed to marshal scale explanation: %v", err)
+		return ""
+	}
+	return string(data)
+}
+
+// withScaleExplanation adds the explanation to a set of audit annotations.
+func withScaleExplanation(auditAnnotations map[string]string, explanation string) map[string]string {
+	if explanation != "" {
+		auditAnnotations[scaleExplanationAnnotation] = explanation
+	}
+	return auditAnnotations
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The scale-out selection policies.
+const (
+	// ScaleOutPolicyMaxThroughput greedily picks the job with the highest
+	// marginal throughput gain, the historical behavior.
+	ScaleOutPolicyMaxThroughput = "max-throughput"
+	// ScaleOutPolicyDRF picks the job whose owner currently holds the
+	// smallest share of elastic workers, dominant-resource-fairness style,
+	// breaking ties by marginal throughput, so one tenant cannot monopolize
+	// idle GPUs.
+	ScaleOutPolicyDRF = "drf"
+)
+
+// ownerLabel groups jobs into tenants for fairness accounting; jobs without
+// it fall back to their namespace.
+const ownerLabel = "scheduler.alpha/owner"
+
+// scaleOutCandidate is one job eligible for scale-out this cycle.
+type scaleOutCandidate struct {
+	name     string
+	delta    int64
+	marginal float64
+	owner    string
+	replicas int64
+	// Context for batch allocation, which re-derives marginals as the job
+	// grows past its first step.
+	profile      []float64
+	launcherGPUs int64
+	maxWorkers   int64
+	constraints  scaleConstraints
+}
+
+// jobOwnerKey returns the tenant a job's usage is accounted against.
+func jobOwnerKey(job *unstructured.Unstructured) string {
+	if owner, check := job.GetLabels()[ownerLabel]; check && owner != "" {
+		return owner
+	}
+	return job.GetNamespace()
+}
+
+// scaleOutAllocation is one job's share of a batch scale-out plan.
+type scaleOutAllocation struct {
+	name  string
+	delta int64
+}
+
+// batchScaleOutPlan distributes up to <idleGPUs> workers across the
+// candidates, repeatedly giving the next constraint-allowed step to the job
+// with the highest marginal throughput for it, until the idle GPUs are
+// spent or no job still gains. The result is ordered by job name so batch
+// execution is deterministic.
+func batchScaleOutPlan(candidates []scaleOutCandidate, idleGPUs int64) []scaleOutAllocation {
+	replicas := make(map[string]int64, len(candidates))
+	deltas := make(map[string]int64, len(candidates))
+	for _, candidate := range candidates {
+		replicas[candidate.name] = candidate.replicas
+	}
+	remaining := idleGPUs
+	for remaining > 0 {
+		best := -1
+		var bestGain float64
+		var bestStep int64
+		for i := range candidates {
+			candidate := &candidates[i]
+			current := replicas[candidate.name]
+			step := candidate.constraints.nextGrowDelta(current, candidate.maxWorkers)
+			if step == 0 || step > remaining {
+				continue
+			}
+			using := current + candidate.launcherGPUs + step
+			if using > dynamicArgs().MaxWorkerReplicas || using >= int64(len(candidate.profile)) {
+				continue
+			}
+			gain := candidate.profile[using] - candidate.profile[using-step]
+			if gain <= 0 {
+				continue
+			}
+			if best == -1 || gain > bestGain {
+				best = i
+				bestGain = gain
+				bestStep = step
+			}
+		}
+		if best == -1 {
+			break
+		}
+		replicas[candidates[best].name] += bestStep
+		deltas[candidates[best].name] += bestStep
+		remaining -= bestStep
+	}
+	var allocations []scaleOutAllocation
+	for i := range candidates {
+		if delta := deltas[candidates[i].name]; delta > 0 {
+			allocations = append(allocations, scaleOutAllocation{name: candidates[i].name, delta: delta})
+		}
+	}
+	return allocations
+}
+
+// pickScaleOutCandidate selects which eligible job to grow under the given
+// selection policy, or nil when there is none.
+func pickScaleOutCandidate(candidates []scaleOutCandidate, policy string) *scaleOutCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if policy != ScaleOutPolicyDRF {
+		best := &candidates[0]
+		for i := range candidates {
+			if candidates[i].marginal > best.marginal {
+				best = &candidates[i]
+			}
+		}
+		return best
+	}
+	// Dominant share per owner is the worker count it already holds across
+	// this cycle's eligible jobs; the least-served owner grows first.
+	ownerShare := make(map[string]int64)
+	for _, candidate := range candidates {
+		ownerShare[candidate.owner] += candidate.replicas
+	}
+	best := &candidates[0]
+	for i := range candidates {
+		candidate := &candidates[i]
+		if ownerShare[candidate.owner] < ownerShare[best.owner] {
+			best = candidate
+		} else if ownerShare[candidate.owner] == ownerShare[best.owner] && candidate.marginal > best.marginal {
+			best = candidate
+		}
+	}
+	return best
+}
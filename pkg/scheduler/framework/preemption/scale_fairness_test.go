@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestJobOwnerKey(t *testing.T) {
+	job := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	job.SetNamespace("my-ns")
+	if got := jobOwnerKey(job); got != "my-ns" {
+		t.Errorf("jobOwnerKey() = %q, want the namespace fallback %q", got, "my-ns")
+	}
+	job.SetLabels(map[string]string{ownerLabel: "team-a"})
+	if got := jobOwnerKey(job); got != "team-a" {
+		t.Errorf("jobOwnerKey() = %q, want the owner label %q", got, "team-a")
+	}
+}
+
+func TestPickScaleOutCandidate(t *testing.T) {
+	candidates := []scaleOutCandidate{
+		{name: "large", marginal: 50, owner: "team-a", replicas: 4},
+		{name: "small", marginal: 30, owner: "team-b", replicas: 1},
+		{name: "tied", marginal: 40, owner: "team-c", replicas: 1},
+	}
+	tests := []struct {
+		name       string
+		candidates []scaleOutCandidate
+		policy     string
+		want       string
+	}{
+		{
+			name:       "max-throughput picks the highest marginal gain",
+			candidates: candidates,
+			policy:     ScaleOutPolicyMaxThroughput,
+			want:       "large",
+		},
+		{
+			name:       "drf picks the least-served owner",
+			candidates: candidates[:2],
+			policy:     ScaleOutPolicyDRF,
+			want:       "small",
+		},
+		{
+			name:       "drf breaks owner-share ties by marginal gain",
+			candidates: candidates,
+			policy:     ScaleOutPolicyDRF,
+			want:       "tied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickScaleOutCandidate(tt.candidates, tt.policy)
+			if got == nil {
+				t.Fatalf("pickScaleOutCandidate() = nil, want %q", tt.want)
+			}
+			if got.name != tt.want {
+				t.Errorf("pickScaleOutCandidate() = %q, want %q", got.name, tt.want)
+			}
+		})
+	}
+	if got := pickScaleOutCandidate(nil, ScaleOutPolicyMaxThroughput); got != nil {
+		t.Errorf("pickScaleOutCandidate(nil) = %v, want nil", got)
+	}
+}
+
+func TestBatchScaleOutPlan(t *testing.T) {
+	// The plan reads MaxWorkerReplicas from the active args; pin the defaults
+	// so the expectations below hold regardless of test order.
+	prevArgs := dynamicArgs()
+	activeDynamicArgs.Store(NewDynamicPreemptionArgs())
+	defer activeDynamicArgs.Store(prevArgs)
+
+	unconstrained := scaleConstraints{step: 1, shrinkable: true}
+	tests := []struct {
+		name       string
+		candidates []scaleOutCandidate
+		idleGPUs   int64
+		want       []scaleOutAllocation
+	}{
+		{
+			name: "second GPU goes to the job with the next-best marginal",
+			candidates: []scaleOutCandidate{
+				{name: "a", replicas: 1, launcherGPUs: 1, maxWorkers: 4, profile: []float64{0, 0, 100, 150, 180, 190}, constraints: unconstrained},
+				{name: "b", replicas: 1, launcherGPUs: 1, maxWorkers: 4, profile: []float64{0, 0, 100, 140, 160, 170}, constraints: unconstrained},
+			},
+			idleGPUs: 2,
+			want: []scaleOutAllocation{
+				{name: "a", delta: 1},
+				{name: "b", delta: 1},
+			},
+		},
+		{
+			name: "constraint step larger than the idle GPUs allocates nothing",
+			candidates: []scaleOutCandidate{
+				{name: "a", replicas: 1, launcherGPUs: 1, maxWorkers: 4, profile: []float64{0, 0, 100, 150, 180, 190}, constraints: scaleConstraints{step: 1, allowedReplicas: []int64{1, 3}, shrinkable: true}},
+			},
+			idleGPUs: 1,
+			want:     nil,
+		},
+		{
+			name: "growth stops at the global replica cap",
+			candidates: []scaleOutCandidate{
+				{name: "a", replicas: 4, launcherGPUs: 1, maxWorkers: 8, profile: []float64{0, 0, 100, 150, 180, 190, 195, 198, 200, 201}, constraints: unconstrained},
+			},
+			idleGPUs: 2,
+			want:     nil,
+		},
+		{
+			name: "no further gain allocates nothing",
+			candidates: []scaleOutCandidate{
+				{name: "a", replicas: 1, launcherGPUs: 1, maxWorkers: 4, profile: []float64{0, 0, 100, 100, 100, 100}, constraints: unconstrained},
+			},
+			idleGPUs: 2,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := batchScaleOutPlan(tt.candidates, tt.idleGPUs)
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(scaleOutAllocation{})); diff != "" {
+				t.Errorf("batchScaleOutPlan() unexpected plan (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ScaleEvent is one entry in the scale-out ledger the scheduler keeps on a
+// job's "scale-out" annotation. The ledger replaces the old plain counter,
+// whose string concatenation turned two +1 scale-outs into "11".
+type ScaleEvent struct {
+	// Delta is the replica change; negative when a scale-out was reverted.
+	Delta int64 `json:"delta"`
+	// Time is when the event was recorded, RFC3339.
+	Time string `json:"time"`
+	// Reason says why, e.g. "scale-out" or "scale-in".
+	Reason string `json:"reason,omitempty"`
+	// Key identifies the decision that produced the event, so a retried
+	// decision can be recognized and never applied twice.
+	Key string `json:"key,omitempty"`
+}
+
+// parseScaleLedger decodes the annotation value into its events. Legacy
+// plain-counter values are converted to a single synthetic event so jobs
+// annotated by an older scheduler keep working; anything unreadable yields
+// an empty ledger.
+func parseScaleLedger(value string) []ScaleEvent {
+	if value == "" {
+		return nil
+	}
+	var events []ScaleEvent
+	if err := json.Unmarshal([]byte(value), &events); err == nil {
+		return events
+	}
+	if legacy, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return []ScaleEvent{{Delta: legacy, Reason: "legacy"}}
+	}
+	klog.Infof("Ignoring unreadable scale-out ledger: %q", value)
+	return nil
+}
+
+// appendScaleEvent returns the annotation value with one more event appended.
+func appendScaleEvent(value string, delta int64, reason string, key string) string {
+	events := append(parseScaleLedger(value), ScaleEvent{
+		Delta:  delta,
+		Time:   time.Now().Format(time.RFC3339),
+		Reason: reason,
+		Key:    key,
+	})
+	encoded, err := json.Marshal(events)
+	if err != nil {
+		klog.Infof("Failed to encode scale-out ledger: %v", err)
+		return value
+	}
+	return string(encoded)
+}
+
+// ledgerHasKey reports whether a decision's event is already in the ledger.
+func ledgerHasKey(value string, key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, event := range parseScaleLedger(value) {
+		if event.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// netScaleOut sums the ledger's deltas: the replicas the scheduler has added
+// and not yet taken back. Never negative.
+func netScaleOut(value string) int64 {
+	var net int64
+	for _, event := range parseScaleLedger(value) {
+		net += event.Delta
+	}
+	if net < 0 {
+		return 0
+	}
+	return net
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScaleLedger(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []ScaleEvent
+	}{
+		{
+			name:  "empty value is an empty ledger",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "legacy counter becomes one synthetic event",
+			value: "2",
+			want:  []ScaleEvent{{Delta: 2, Reason: "legacy"}},
+		},
+		{
+			name:  "ledger round-trips through JSON",
+			value: `[{"delta":1,"time":"2024-06-01T12:00:00Z","reason":"scale-out","key":"k1"},{"delta":-1,"time":"2024-06-01T13:00:00Z","reason":"scale-in"}]`,
+			want: []ScaleEvent{
+				{Delta: 1, Time: "2024-06-01T12:00:00Z", Reason: "scale-out", Key: "k1"},
+				{Delta: -1, Time: "2024-06-01T13:00:00Z", Reason: "scale-in"},
+			},
+		},
+		{
+			name:  "unreadable value is an empty ledger",
+			value: "not-a-ledger",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseScaleLedger(tt.value)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseScaleLedger() unexpected result (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppendScaleEvent(t *testing.T) {
+	value := appendScaleEvent("", 1, "scale-out", "k1")
+	value = appendScaleEvent(value, 1, "scale-out", "k2")
+
+	events := parseScaleLedger(value)
+	if len(events) != 2 {
+		t.Fatalf("parseScaleLedger() returned %d events, want 2", len(events))
+	}
+	if events[0].Key != "k1" || events[1].Key != "k2" {
+		t.Errorf("appendScaleEvent() keys = %q, %q, want k1, k2", events[0].Key, events[1].Key)
+	}
+	// Two +1 events must sum to 2, not concatenate to "11" like the old
+	// plain counter did.
+	if got := netScaleOut(value); got != 2 {
+		t.Errorf("netScaleOut() = %v, want 2", got)
+	}
+}
+
+// TestAppendScaleEventLegacyUpgrade checks appending to a legacy counter
+// keeps the old delta as a synthetic event.
+func TestAppendScaleEventLegacyUpgrade(t *testing.T) {
+	value := appendScaleEvent("2", -1, "scale-in", "")
+	events := parseScaleLedger(value)
+	if len(events) != 2 {
+		t.Fatalf("parseScaleLedger() returned %d events, want 2", len(events))
+	}
+	if events[0].Reason != "legacy" || events[0].Delta != 2 {
+		t.Errorf("first event = %+v, want the legacy counter preserved", events[0])
+	}
+	if got := netScaleOut(value); got != 1 {
+		t.Errorf("netScaleOut() = %v, want 1", got)
+	}
+}
+
+func TestLedgerHasKey(t *testing.T) {
+	value := appendScaleEvent("", 1, "scale-out", "k1")
+	tests := []struct {
+		name  string
+		value string
+		key   string
+		want  bool
+	}{
+		{
+			name:  "recorded key is found",
+			value: value,
+			key:   "k1",
+			want:  true,
+		},
+		{
+			name:  "unknown key is not found",
+			value: value,
+			key:   "k2",
+			want:  false,
+		},
+		{
+			name:  "empty key never matches",
+			value: value,
+			key:   "",
+			want:  false,
+		},
+		{
+			name:  "legacy counters carry no keys",
+			value: "2",
+			key:   "k1",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ledgerHasKey(tt.value, tt.key); got != tt.want {
+				t.Errorf("ledgerHasKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetScaleOut(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int64
+	}{
+		{
+			name:  "empty ledger nets zero",
+			value: "",
+			want:  0,
+		},
+		{
+			name:  "deltas are summed",
+			value: `[{"delta":2},{"delta":1},{"delta":-1}]`,
+			want:  2,
+		},
+		{
+			name:  "net never goes negative",
+			value: `[{"delta":1},{"delta":-3}]`,
+			want:  0,
+		},
+		{
+			name:  "legacy counter is its own net",
+			value: "3",
+			want:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netScaleOut(tt.value); got != tt.want {
+				t.Errorf("netScaleOut(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
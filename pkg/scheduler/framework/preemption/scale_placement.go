@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// NodeBandwidthLabel carries a node's inter-node network bandwidth in Gbps,
+// e.g. set by infrastructure tooling from the NIC speed. Unlabeled nodes are
+// assumed to have the default bandwidth, so clusters without the label see
+// no placement restriction.
+const NodeBandwidthLabel = "scheduler.alpha/network-gbps"
+
+// defaultNodeBandwidthGbps is assumed for unlabeled nodes.
+const defaultNodeBandwidthGbps = 10.0
+
+// bandwidthCollapseFactor is the fraction of the job's slowest current link
+// below which a new worker is not worth adding: all-reduce runs at the speed
+// of the slowest member, so one worker across a much slower link drags the
+// whole ring down past the marginal throughput the extra GPU brings.
+const bandwidthCollapseFactor = 0.5
+
+// nodeBandwidthGbps reads the node's bandwidth label, defaulting when the
+// label is absent or malformed.
+func nodeBandwidthGbps(node *v1.Node) float64 {
+	bwStr, check := node.Labels[NodeBandwidthLabel]
+	if !check {
+		return defaultNodeBandwidthGbps
+	}
+	bw, err := strconv.ParseFloat(bwStr, 64)
+	if err != nil || bw <= 0 {
+		klog.Infof("Ignoring malformed %v on %v: %q", NodeBandwidthLabel, node.Name, bwStr)
+		return defaultNodeBandwidthGbps
+	}
+	return bw
+}
+
+// Co-location modes for DynamicPreemptionArgs.ScaleOutColocation.
+const (
+	ColocationNode = "node"
+	ColocationZone = "zone"
+)
+
+// placementConstraint decides whether a new worker may land on the candidate
+// node, given the nodes already hosting the job's pods. Constraints are
+// consulted during scale-out candidate selection so replicas are only
+// incremented when an acceptable landing spot exists.
+type placementConstraint func(candidate *v1.Node, jobNodes []*v1.Node) bool
+
+// activePlacementConstraints returns the constraints to apply in order; a
+// candidate node must pass all of them.
+func activePlacementConstraints() []placementConstraint {
+	constraints := []placementConstraint{bandwidthConstraint}
+	if dynamicArgs().ScaleOutColocation != "" {
+		constraints = append(constraints, colocationConstraint(dynamicArgs().ScaleOutColocation))
+	}
+	return constraints
+}
+
+// colocationConstraint restricts the candidate to the job's current nodes
+// (mode "node") or topology zones (mode "zone"). Jobs with no located pods
+// are unrestricted, otherwise a fresh job could never place its first worker.
+func colocationConstraint(mode string) placementConstraint {
+	return func(candidate *v1.Node, jobNodes []*v1.Node) bool {
+		if len(jobNodes) == 0 {
+			return true
+		}
+		if mode == ColocationNode {
+			for _, node := range jobNodes {
+				if node.Name == candidate.Name {
+					return true
+				}
+			}
+			return false
+		}
+		candidateZone := candidate.Labels[v1.LabelTopologyZone]
+		for _, node := range jobNodes {
+			if node.Labels[v1.LabelTopologyZone] == candidateZone {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// bandwidthConstraint rejects candidate nodes whose network is slower than
+// bandwidthCollapseFactor times the job's current slowest link.
+func bandwidthConstraint(candidate *v1.Node, jobNodes []*v1.Node) bool {
+	if len(jobNodes) == 0 {
+		return true
+	}
+	slowest := nodeBandwidthGbps(jobNodes[0])
+	for _, node := range jobNodes[1:] {
+		if bw := nodeBandwidthGbps(node); bw < slowest {
+			slowest = bw
+		}
+	}
+	return nodeBandwidthGbps(candidate) >= slowest*bandwidthCollapseFactor
+}
+
+// jobNodes returns the nodes currently hosting the job's pods.
+func (ev *Evaluator) jobNodes(ctx context.Context, nodes *v1.NodeList, MPIJobName string) []*v1.Node {
+	var hosting []*v1.Node
+	// The placement store answers this without touching every node's pods;
+	// the walk below remains for jobs bound before the scheduler started.
+	if hostingNames, tracked := jobPlacementNodes(MPIJobName); tracked && ev.Snapshot == nil {
+		for i := range nodes.Items {
+			if hostingNames[nodes.Items[i].Name] {
+				hosting = append(hosting, &nodes.Items[i])
+			}
+		}
+		return hosting
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		pods, err := ev.podsOnNode(ctx, node.Name)
+		if err != nil {
+			continue
+		}
+		for _, pod := range pods {
+			if strings.HasPrefix(pod.Name, MPIJobName+"-") {
+				hosting = append(hosting, node)
+				break
+			}
+		}
+	}
+	return hosting
+}
+
+// nodeIdleGPUs returns the node's unallocated GPU count.
+func (ev *Evaluator) nodeIdleGPUs(ctx context.Context, node *v1.Node) int64 {
+	if ev.Snapshot == nil {
+		if idle, ready := NodeIdleGPUs(node.Name); ready {
+			return idle
+		}
+	}
+	capacity := nodeAcceleratorCapacity(node)
+	if capacity == 0 {
+		return 0
+	}
+	pods, err := ev.podsOnNode(ctx, node.Name)
+	if err != nil {
+		klog.Infof("Pod loading error")
+		return 0
+	}
+	var allocated int64
+	for _, pod := range pods {
+		allocated += podAcceleratorRequest(pod) + ev.podDRAGPUs(ctx, pod)
+	}
+	return capacity - allocated
+}
+
+// scaleOutPlacementFeasible reports whether at least one node with an idle
+// GPU passes every placement constraint for the job, i.e. the new worker has
+// somewhere acceptable to land. Incrementing replicas without this check
+// just parks a pending worker pod, or worse, lands it across a slow link.
+func (ev *Evaluator) scaleOutPlacementFeasible(ctx context.Context, nodes *v1.NodeList, MPIJobName string) bool {
+	hosting := ev.jobNodes(ctx, nodes, MPIJobName)
+	constraints := activePlacementConstraints()
+	for i := range nodes.Items {
+		candidate := &nodes.Items[i]
+		if ev.nodeIdleGPUs(ctx, candidate) <= 0 {
+			continue
+		}
+		allowed := true
+		for _, constraint := range constraints {
+			if !constraint(candidate, hosting) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return true
+		}
+	}
+	klog.Infof("Scale-out of %v has no acceptable landing node", MPIJobName)
+	return false
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ScalePermissionPolicy restricts which jobs the scheduler may scale, so
+// multi-team clusters can opt particular workloads out of scheduler-driven
+// scaling entirely. Denials always win over allowances; empty allow fields
+// allow everything.
+type ScalePermissionPolicy struct {
+	// AllowedNamespaces, when non-empty, limits scaling to jobs in these
+	// namespaces.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// DeniedNamespaces excludes jobs in these namespaces.
+	DeniedNamespaces []string `json:"deniedNamespaces,omitempty"`
+	// AllowLabelSelector, when non-empty, limits scaling to jobs matching
+	// these labels.
+	AllowLabelSelector map[string]string `json:"allowLabelSelector,omitempty"`
+	// DenyLabelSelector excludes jobs matching these labels.
+	DenyLabelSelector map[string]string `json:"denyLabelSelector,omitempty"`
+}
+
+// Permits reports whether the policy lets the scheduler scale a job in
+// <namespace> carrying <jobLabels>.
+func (policy *ScalePermissionPolicy) Permits(namespace string, jobLabels map[string]string) bool {
+	if policy == nil {
+		return true
+	}
+	if Contains(policy.DeniedNamespaces, namespace) {
+		return false
+	}
+	if len(policy.DenyLabelSelector) > 0 && labels.SelectorFromSet(policy.DenyLabelSelector).Matches(labels.Set(jobLabels)) {
+		return false
+	}
+	if len(policy.AllowedNamespaces) > 0 && !Contains(policy.AllowedNamespaces, namespace) {
+		return false
+	}
+	if len(policy.AllowLabelSelector) > 0 && !labels.SelectorFromSet(policy.AllowLabelSelector).Matches(labels.Set(jobLabels)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// scaleIntent is one deferred replica mutation. With the async executor
+// enabled, the scheduling cycle only records the intent and returns; a slow
+// API server delays the mutation, not PostFilter.
+type scaleIntent struct {
+	namespace        string
+	jobName          string
+	delta            int64
+	auditAnnotations map[string]string
+}
+
+// The queue carries intent keys; the intents themselves live in the map,
+// since work queue items must be comparable. Re-enqueueing the same key
+// while its intent is pending dedupes naturally.
+var (
+	scaleIntentsLock sync.Mutex
+	scaleIntents     = make(map[string]scaleIntent)
+	scaleQueue       = workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{Name: "dynamic_scale"})
+)
+
+// enqueueScaleIntent records the mutation and hands its key to the queue.
+func enqueueScaleIntent(ns string, jobName string, delta int64, auditAnnotations map[string]string) {
+	key := fmt.Sprintf("%s/%s/%d/%s", ns, jobName, delta, auditAnnotations["audit.scheduling.k8s.io/plan-uid"])
+	scaleIntentsLock.Lock()
+	scaleIntents[key] = scaleIntent{namespace: ns, jobName: jobName, delta: delta, auditAnnotations: auditAnnotations}
+	scaleIntentsLock.Unlock()
+	scaleQueue.Add(key)
+}
+
+// RunScaleExecutor drains queued scale intents with retries and backoff
+// until ctx is done. Failed mutations are re-enqueued rate-limited, so a
+// burst of unschedulable pods cannot stall scheduling cycles on MPIJob
+// updates.
+func (ev *Evaluator) RunScaleExecutor(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		scaleQueue.ShutDown()
+	}()
+	for ev.processNextScaleIntent(ctx) {
+	}
+}
+
+func (ev *Evaluator) processNextScaleIntent(ctx context.Context) bool {
+	item, shutdown := scaleQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer scaleQueue.Done(item)
+	key := item.(string)
+	scaleIntentsLock.Lock()
+	intent, check := scaleIntents[key]
+	scaleIntentsLock.Unlock()
+	if !check {
+		scaleQueue.Forget(item)
+		return true
+	}
+	if err := ev.elasticJobScaling(ctx, mpiJobAdapter, intent.namespace, intent.jobName, intent.delta, intent.auditAnnotations); err != nil {
+		klog.Infof("Deferred scaling of %v failed, requeueing: %v", intent.jobName, err)
+		scaleQueue.AddRateLimited(item)
+		return true
+	}
+	scaleQueue.Forget(item)
+	scaleIntentsLock.Lock()
+	delete(scaleIntents, key)
+	scaleIntentsLock.Unlock()
+	return true
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// ClusterSnapshot captures the inputs Dynamic consults, detached from any
+// API server: nodes, pods (with NodeName and annotations set), elastic jobs
+// and optionally a throughput table. Simulate works entirely off the
+// snapshot, so policies can be evaluated offline against recorded cluster
+// states and regression-tested without a cluster.
+type ClusterSnapshot struct {
+	Nodes       []v1.Node
+	Pods        []v1.Pod
+	ElasticJobs []*unstructured.Unstructured
+	// ModelData overrides the active throughput table; nil uses it.
+	ModelData map[string][]float64
+}
+
+// podsOnNode returns the snapshot pods bound to the node.
+func (snapshot *ClusterSnapshot) podsOnNode(nodeName string) []*v1.Pod {
+	var pods []*v1.Pod
+	for i := range snapshot.Pods {
+		if snapshot.Pods[i].Spec.NodeName == nodeName {
+			pods = append(pods, &snapshot.Pods[i])
+		}
+	}
+	return pods
+}
+
+// elasticJob returns the snapshot job with the given name, or nil.
+func (snapshot *ClusterSnapshot) elasticJob(name string) *unstructured.Unstructured {
+	for _, job := range snapshot.ElasticJobs {
+		if job.GetName() == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// SimulatedDecision is the decision Dynamic would make for a pending pod.
+type SimulatedDecision struct {
+	// Action is "fits" (idle GPUs already cover the pod), "retrieve",
+	// "scale-out" or "none".
+	Action string
+	// Plan is the retrieve plan, set when Action is "retrieve".
+	Plan *RetrievePlan
+	// ScaleOutJob and ScaleOutDelta describe the chosen scale-out, set when
+	// Action is "scale-out".
+	ScaleOutJob   string
+	ScaleOutDelta int64
+	// Reason explains the decision in the same words Dynamic would use.
+	Reason string
+}
+
+// parallelUntil runs fn for every index, through the framework parallelizer
+// when a Handle is present and sequentially in offline simulation.
+func (ev *Evaluator) parallelUntil(ctx context.Context, pieces int, fn func(int)) {
+	if ev.Handler != nil {
+		ev.Handler.Parallelizer().Until(ctx, pieces, fn, ev.PluginName)
+		return
+	}
+	for i := 0; i < pieces; i++ {
+		fn(i)
+	}
+}
+
+// Simulate returns the decision Dynamic would make for <pod> against the
+// snapshot, mutating nothing. The planning and selection code is the same
+// the live path runs; only the data sources differ.
+func Simulate(snapshot *ClusterSnapshot, pod *v1.Pod) *SimulatedDecision {
+	ctx := context.Background()
+	ev := &Evaluator{PluginName: "Simulator", Snapshot: snapshot}
+	nodes := &v1.NodeList{Items: snapshot.Nodes}
+	scalableModelData := snapshot.ModelData
+	if scalableModelData == nil {
+		scalableModelData = activeScalableModelData()
+	}
+
+	requestGPUs := podAcceleratorRequest(pod)
+	idleGPUs := ev.idleGPUsinNodes(ctx, nodes)
+	if idleGPUs >= requestGPUs {
+		return &SimulatedDecision{Action: "fits", Reason: "idle GPUs cover the request"}
+	}
+
+	var runningMPIJobs []string
+	for _, job := range snapshot.ElasticJobs {
+		runningMPIJobs = append(runningMPIJobs, job.GetName())
+	}
+
+	plan := ev.PlanRetrieve(ctx, pod, scalableModelData, nodes, idleGPUs, requestGPUs, runningMPIJobs)
+	if plan.Feasible {
+		return &SimulatedDecision{Action: "retrieve", Plan: plan, Reason: "retractions and scale-ins free enough GPUs"}
+	}
+	if idleGPUs == 0 {
+		return &SimulatedDecision{Action: "none", Plan: plan, Reason: "Insufficient idle GPUs: " + ev.gpuShortageDiagnostics(ctx, nodes, plan, requestGPUs, idleGPUs)}
+	}
+
+	var candidates []scaleOutCandidate
+	for _, job := range snapshot.ElasticJobs {
+		if !namespaceInScope(pod, job.GetNamespace()) {
+			continue
+		}
+		if mpiJobBudgetExhausted(job) {
+			continue
+		}
+		if !elasticJobRunning(job) {
+			continue
+		}
+		annotations := job.GetAnnotations()
+		replicas, found, err := unstructured.NestedInt64(job.Object, "spec", "mpiReplicaSpecs", "Worker", "replicas")
+		if err != nil || !found {
+			continue
+		}
+		_, maxWorkers := mpiJobWorkerBounds(job)
+		constraints := parseScaleConstraints(annotations)
+		growDelta := constraints.nextGrowDelta(replicas, maxWorkers)
+		if growDelta == 0 {
+			continue
+		}
+		launcherGPUs := mpiJobLauncherGPUs(job)
+		usingGPUs := replicas + launcherGPUs + growDelta
+		if usingGPUs > dynamicArgs().MaxWorkerReplicas {
+			continue
+		}
+		modelProfile, knownModel := profileForModelBatch(scalableModelData, annotations[util.ModelNameAnnotation], annotations[batchSizeAnnotation])
+		if !knownModel {
+			if dynamicArgs().UnknownModelPolicy == UnknownModelUnschedulable {
+				return &SimulatedDecision{Action: "none", Plan: plan, Reason: "Model " + annotations[util.ModelNameAnnotation] + " is absent from the throughput table"}
+			}
+			continue
+		}
+		// The profile (arbitrary length when loaded from a ModelSource file)
+		// must cover the grown worker count; MaxWorkerReplicas alone does not
+		// guarantee that.
+		if usingGPUs >= int64(len(modelProfile)) || usingGPUs-growDelta < 0 {
+			continue
+		}
+		marginal := modelProfile[usingGPUs] - modelProfile[usingGPUs-growDelta]
+		if marginal <= 0 {
+			continue
+		}
+		for growDelta < dynamicArgs().MaxScaleOutStep && growDelta < idleGPUs {
+			next := constraints.nextGrowDelta(replicas+growDelta, maxWorkers)
+			if next == 0 || growDelta+next > dynamicArgs().MaxScaleOutStep || growDelta+next > idleGPUs {
+				break
+			}
+			using := replicas + launcherGPUs + growDelta + next
+			if using > dynamicArgs().MaxWorkerReplicas || using >= int64(len(modelProfile)) {
+				break
+			}
+			gain := modelProfile[using] - modelProfile[using-next]
+			if gain <= 0 {
+				break
+			}
+			growDelta += next
+			marginal += gain
+		}
+		if netScaleOut(annotations[util.ScaleOutAnnotation]) > 0 {
+			continue
+		}
+		if !ev.scaleOutPlacementFeasible(ctx, nodes, job.GetName()) {
+			continue
+		}
+		candidates = append(candidates, scaleOutCandidate{
+			name:     job.GetName(),
+			delta:    growDelta,
+			marginal: marginal,
+			owner:    jobOwnerKey(job),
+			replicas: replicas,
+		})
+	}
+	if chosen := pickScaleOutCandidate(candidates, dynamicArgs().ScaleOutPolicy); chosen != nil {
+		return &SimulatedDecision{Action: "scale-out", ScaleOutJob: chosen.name, ScaleOutDelta: chosen.delta, Reason: "Scale-Out MPIJob"}
+	}
+	return &SimulatedDecision{Action: "none", Plan: plan, Reason: "Idle GPUs unabsorbed: all elastic jobs at their scaling ceiling"}
+}
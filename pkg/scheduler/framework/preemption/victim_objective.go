@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sort"
+	"sync"
+)
+
+// The ways a RetrievePolicy can trade retraction against scale-in when both
+// could free enough GPUs.
+const (
+	RetrievePolicyPreferRetract = "prefer-retract"
+	RetrievePolicyPreferScaleIn = "prefer-scale-in"
+	RetrievePolicyRetractOnly   = "retract-only"
+	RetrievePolicyScaleInOnly   = "scale-in-only"
+)
+
+// Names of the sort dimensions a VictimSortOrder may list.
+const (
+	VictimSortDeadline = "deadline"
+	VictimSortRuntime  = "runtime"
+	VictimSortCost     = "cost"
+	VictimSortGPUs     = "gpus"
+	VictimSortPriority = "priority"
+	VictimSortPDB      = "pdb"
+)
+
+// defaultVictimSortOrder is the historical ranking with run time added:
+// among equally cheap candidates, the pod that has done the least work is
+// retracted first.
+var defaultVictimSortOrder = []string{VictimSortDeadline, VictimSortRuntime, VictimSortCost, VictimSortGPUs, VictimSortPriority, VictimSortPDB}
+
+// RetrieveCandidate is the view of one retraction or scale-in candidate an
+// objective ranks. It decouples objectives from the internal candidate
+// rows, so experiments do not fork the planner.
+type RetrieveCandidate struct {
+	// ScaleIn is true for a job scale-in, false for a pod retraction.
+	ScaleIn bool
+	// GPUsFreed is the number of GPUs the candidate gives back.
+	GPUsFreed int
+	// ThroughputLoss is the estimated throughput lost.
+	ThroughputLoss int
+	// PDBViolation is true when eviction violates a PodDisruptionBudget.
+	PDBViolation bool
+	// EvictionCost is the unified eviction cost, including GPU utilization
+	// when a metrics source is configured.
+	EvictionCost int
+	// DeadlineDistanceSeconds is the time left until the candidate's
+	// declared deadline; noDeadline when it has none.
+	DeadlineDistanceSeconds int
+	// Priority is the workload's scheduling priority.
+	Priority int
+	// RuntimeSeconds is how long a retraction victim has been running; zero
+	// for scale-ins, which lose no completed work.
+	RuntimeSeconds int
+}
+
+// CandidateObjective orders retrieve candidates: candidates ranking first
+// are taken into the plan first. Implementations must be pure functions of
+// their inputs; Less is called from concurrent planning passes.
+type CandidateObjective interface {
+	// Name identifies the objective in VictimObjective and logs.
+	Name() string
+	// Less reports whether candidate a should be taken before candidate b.
+	Less(a, b RetrieveCandidate) bool
+}
+
+var (
+	objectiveLock       sync.Mutex
+	candidateObjectives = map[string]CandidateObjective{}
+)
+
+func init() {
+	RegisterCandidateObjective(lexicographicObjective{})
+}
+
+// RegisterCandidateObjective makes an objective selectable through the
+// victimObjective argument. Out-of-tree objectives register themselves in
+// an init function, the same way plugins join the registry.
+func RegisterCandidateObjective(objective CandidateObjective) {
+	objectiveLock.Lock()
+	defer objectiveLock.Unlock()
+	candidateObjectives[objective.Name()] = objective
+}
+
+// candidateObjectiveKnown reports whether name resolves to an objective.
+func candidateObjectiveKnown(name string) bool {
+	if name == "" {
+		return true
+	}
+	objectiveLock.Lock()
+	defer objectiveLock.Unlock()
+	_, known := candidateObjectives[name]
+	return known
+}
+
+// activeCandidateObjective resolves the configured objective, falling back
+// to the built-in lexicographic ranking.
+func activeCandidateObjective() CandidateObjective {
+	objectiveLock.Lock()
+	defer objectiveLock.Unlock()
+	if objective, known := candidateObjectives[dynamicArgs().VictimObjective]; known {
+		return objective
+	}
+	return lexicographicObjective{}
+}
+
+// sortRetrieveCandidates ranks the planner's candidate rows under the
+// active objective.
+func sortRetrieveCandidates(retrieveCandidates [][]int) {
+	objective := activeCandidateObjective()
+	sort.SliceStable(retrieveCandidates, func(i, j int) bool {
+		return objective.Less(candidateView(retrieveCandidates[i]), candidateView(retrieveCandidates[j]))
+	})
+	// The action-type preference dominates every objective: a policy that
+	// prefers shrinking jobs only retracts pods once scale-ins run out, and
+	// vice versa.
+	switch dynamicArgs().RetrievePolicy {
+	case RetrievePolicyPreferRetract:
+		sort.SliceStable(retrieveCandidates, func(i, j int) bool {
+			return retrieveCandidates[i][0] < retrieveCandidates[j][0]
+		})
+	case RetrievePolicyPreferScaleIn:
+		sort.SliceStable(retrieveCandidates, func(i, j int) bool {
+			return retrieveCandidates[i][0] > retrieveCandidates[j][0]
+		})
+	}
+}
+
+// candidateView converts an internal candidate row into the stable view
+// objectives see.
+func candidateView(row []int) RetrieveCandidate {
+	return RetrieveCandidate{
+		ScaleIn:                 row[0] == 1,
+		GPUsFreed:               row[2],
+		ThroughputLoss:          row[3],
+		PDBViolation:            row[4] == 1,
+		EvictionCost:            row[5],
+		DeadlineDistanceSeconds: row[6],
+		Priority:                row[7],
+		RuntimeSeconds:          row[8],
+	}
+}
+
+// lexicographicObjective is the built-in ranking: the configured (or
+// default) VictimSortOrder applied as a lexicographic comparison, strongest
+// dimension first.
+type lexicographicObjective struct{}
+
+func (lexicographicObjective) Name() string { return "lexicographic" }
+
+func (lexicographicObjective) Less(a, b RetrieveCandidate) bool {
+	order := dynamicArgs().VictimSortOrder
+	if len(order) == 0 {
+		order = defaultVictimSortOrder
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		aValue, descending := dimensionValue(a, order[i])
+		bValue, _ := dimensionValue(b, order[i])
+		if aValue == bValue {
+			continue
+		}
+		if descending {
+			return aValue > bValue
+		}
+		return aValue < bValue
+	}
+	return false
+}
+
+// dimensionValue extracts one named dimension and whether larger values
+// rank earlier.
+func dimensionValue(candidate RetrieveCandidate, name string) (value int, descending bool) {
+	switch name {
+	case VictimSortDeadline:
+		return candidate.DeadlineDistanceSeconds, true
+	case VictimSortRuntime:
+		return candidate.RuntimeSeconds, false
+	case VictimSortCost:
+		return candidate.EvictionCost, false
+	case VictimSortGPUs:
+		return candidate.GPUsFreed, false
+	case VictimSortPriority:
+		return candidate.Priority, false
+	case VictimSortPDB:
+		if candidate.PDBViolation {
+			return 1, false
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// victimSortDimensionKnown reports whether name is a valid sort dimension.
+func victimSortDimensionKnown(name string) bool {
+	switch name {
+	case VictimSortDeadline, VictimSortRuntime, VictimSortCost, VictimSortGPUs, VictimSortPriority, VictimSortPDB:
+		return true
+	}
+	return false
+}
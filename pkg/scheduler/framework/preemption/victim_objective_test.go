@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pinDynamicArgs installs fresh default args for the test and restores the
+// previous configuration afterwards.
+func pinDynamicArgs(t *testing.T) *DynamicPreemptionArgs {
+	t.Helper()
+	prevArgs := dynamicArgs()
+	args := NewDynamicPreemptionArgs()
+	activeDynamicArgs.Store(args)
+	t.Cleanup(func() { activeDynamicArgs.Store(prevArgs) })
+	return args
+}
+
+func TestLexicographicObjectiveDefaultOrder(t *testing.T) {
+	pinDynamicArgs(t)
+
+	base := RetrieveCandidate{GPUsFreed: 2, EvictionCost: 10, DeadlineDistanceSeconds: 600, Priority: 100, RuntimeSeconds: 60}
+	tests := []struct {
+		name string
+		a, b RetrieveCandidate
+		want bool
+	}{
+		{
+			name: "avoiding a PDB violation dominates everything",
+			a:    base,
+			b:    func() RetrieveCandidate { c := base; c.PDBViolation = true; c.EvictionCost = 0; return c }(),
+			want: true,
+		},
+		{
+			name: "lower priority ranks first",
+			a:    func() RetrieveCandidate { c := base; c.Priority = 10; return c }(),
+			b:    base,
+			want: true,
+		},
+		{
+			name: "fewer GPUs freed ranks first",
+			a:    func() RetrieveCandidate { c := base; c.GPUsFreed = 1; return c }(),
+			b:    base,
+			want: true,
+		},
+		{
+			name: "cheaper eviction ranks first",
+			a:    func() RetrieveCandidate { c := base; c.EvictionCost = 1; return c }(),
+			b:    base,
+			want: true,
+		},
+		{
+			name: "shorter runtime ranks first",
+			a:    func() RetrieveCandidate { c := base; c.RuntimeSeconds = 5; return c }(),
+			b:    base,
+			want: true,
+		},
+		{
+			name: "farther deadline ranks first",
+			a:    func() RetrieveCandidate { c := base; c.DeadlineDistanceSeconds = 3600; return c }(),
+			b:    base,
+			want: true,
+		},
+		{
+			name: "equal candidates are not less",
+			a:    base,
+			b:    base,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (lexicographicObjective{}).Less(tt.a, tt.b); got != tt.want {
+				t.Errorf("Less() = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				if reverse := (lexicographicObjective{}).Less(tt.b, tt.a); reverse {
+					t.Errorf("Less() is not antisymmetric: both orders report true")
+				}
+			}
+		})
+	}
+}
+
+// TestLexicographicObjectiveCustomOrder checks a configured VictimSortOrder
+// replaces the default ranking entirely.
+func TestLexicographicObjectiveCustomOrder(t *testing.T) {
+	args := pinDynamicArgs(t)
+	args.VictimSortOrder = []string{VictimSortCost}
+
+	cheapViolator := RetrieveCandidate{EvictionCost: 1, PDBViolation: true}
+	expensive := RetrieveCandidate{EvictionCost: 10}
+	if !(lexicographicObjective{}).Less(cheapViolator, expensive) {
+		t.Errorf("Less() must rank by cost alone when the order lists only %q", VictimSortCost)
+	}
+}
+
+// objectiveRow builds a candidate row with the columns candidateView reads.
+func objectiveRow(scaleIn, pdb, cost int) []int {
+	return []int{scaleIn, 0, 2, 0, pdb, cost, 600, 100, 60}
+}
+
+func TestSortRetrieveCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		candidates [][]int
+		want       [][]int
+	}{
+		{
+			name:   "objective orders rows regardless of action type",
+			policy: "",
+			candidates: [][]int{
+				objectiveRow(0, 0, 10),
+				objectiveRow(1, 0, 1),
+			},
+			want: [][]int{
+				objectiveRow(1, 0, 1),
+				objectiveRow(0, 0, 10),
+			},
+		},
+		{
+			name:   "prefer-retract puts retractions before cheaper scale-ins",
+			policy: RetrievePolicyPreferRetract,
+			candidates: [][]int{
+				objectiveRow(1, 0, 1),
+				objectiveRow(0, 0, 10),
+			},
+			want: [][]int{
+				objectiveRow(0, 0, 10),
+				objectiveRow(1, 0, 1),
+			},
+		},
+		{
+			name:   "prefer-scale-in puts scale-ins before cheaper retractions",
+			policy: RetrievePolicyPreferScaleIn,
+			candidates: [][]int{
+				objectiveRow(0, 0, 1),
+				objectiveRow(1, 0, 10),
+			},
+			want: [][]int{
+				objectiveRow(1, 0, 10),
+				objectiveRow(0, 0, 1),
+			},
+		},
+		{
+			name:   "objective still orders within an action type",
+			policy: RetrievePolicyPreferRetract,
+			candidates: [][]int{
+				objectiveRow(0, 1, 1),
+				objectiveRow(0, 0, 10),
+				objectiveRow(1, 0, 5),
+			},
+			want: [][]int{
+				objectiveRow(0, 0, 10),
+				objectiveRow(0, 1, 1),
+				objectiveRow(1, 0, 5),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := pinDynamicArgs(t)
+			args.RetrievePolicy = tt.policy
+			sortRetrieveCandidates(tt.candidates)
+			if diff := cmp.Diff(tt.want, tt.candidates); diff != "" {
+				t.Errorf("sortRetrieveCandidates() unexpected order (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
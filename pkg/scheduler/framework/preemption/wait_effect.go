@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// waitForEffectPollInterval is how often executed-plan effects are rechecked.
+const waitForEffectPollInterval = time.Second
+
+// waitForPlanEffect optionally blocks until every retracted victim is
+// actually deleted from the API, so the GPUs a nomination promises are
+// really reclaimable and the preemptor does not burn bind attempts while
+// kubelets release devices. Disabled (the default) or settled returns true;
+// a timeout returns false and the caller nominates no node, letting a later
+// cycle find the freed capacity.
+func (ev *Evaluator) waitForPlanEffect(ctx context.Context, plan *RetrievePlan) bool {
+	if dynamicArgs().WaitForEffectSeconds == 0 {
+		return true
+	}
+	deadline := time.Now().Add(time.Duration(dynamicArgs().WaitForEffectSeconds) * time.Second)
+	for {
+		settled := true
+		for _, action := range plan.Actions {
+			if !action.Retract {
+				continue
+			}
+			_, err := ev.Handler.ClientSet().CoreV1().Pods(action.VictimPod.Namespace).Get(ctx, action.VictimPod.Name, metav1.GetOptions{})
+			if err == nil || !apierrors.IsNotFound(err) {
+				settled = false
+				break
+			}
+		}
+		if settled {
+			return true
+		}
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(waitForEffectPollInterval)
+	}
+}
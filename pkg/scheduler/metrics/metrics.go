@@ -99,6 +99,63 @@ var (
 			Help:           "Total preemption attempts in the cluster till now",
 			StabilityLevel: metrics.STABLE,
 		})
+	ElasticAttemptsSuppressed = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "elastic_attempts_suppressed_total",
+			Help:           "Number of elastic scale/retract plans suppressed because the pod already executed a plan in the current backoff period",
+			StabilityLevel: metrics.ALPHA,
+		})
+	ShadowPlanDivergence = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "shadow_plan_divergence_total",
+			Help:           "Number of elastic plans where the shadow policy diverged from the active policy, by divergence kind ('victim_count' or 'gpus_freed')",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"kind"})
+	IdleGPUsPerNode = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "idle_gpus_per_node",
+			Help:           "Idle GPUs on each node as computed by the elastic accounting pass",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"node"})
+	IdleGPUsTotal = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "idle_gpus_total",
+			Help:           "Cluster-wide idle GPUs as computed by the elastic accounting pass",
+			StabilityLevel: metrics.ALPHA,
+		})
+	DynamicPlansExecuted = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "dynamic_plans_executed_total",
+			Help:           "Number of elastic plans executed by the Dynamic path, by scheduler profile and plan action type",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"profile", "action"})
+	PlanToBoundSeconds = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "plan_to_bound_seconds",
+			Help:           "Latency from the Dynamic PostFilter executing an elastic plan to the preemptor being bound, by plan action type ('retract', 'scale-in', 'mixed' or 'scale-out')",
+			Buckets:        metrics.ExponentialBuckets(1, 2, 10),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"action"})
+	IdleGPUsUnabsorbed = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "idle_gpus_unabsorbed",
+			Help:           "Idle GPUs that no elastic job could absorb in the last Dynamic cycle because every job was at its scaling ceiling",
+			StabilityLevel: metrics.ALPHA,
+		})
+	ElasticDegradedMode = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Subsystem:      SchedulerSubsystem,
+			Name:           "elastic_degraded_mode",
+			Help:           "Set to 1 while the MPIJob CRD is absent and elastic scale features are disabled; backfill and retraction stay active",
+			StabilityLevel: metrics.ALPHA,
+		})
 	pendingPods = metrics.NewGaugeVec(
 		&metrics.GaugeOpts{
 			Subsystem:      SchedulerSubsystem,
@@ -219,6 +276,14 @@ var (
 		SchedulingAlgorithmLatency,
 		PreemptionVictims,
 		PreemptionAttempts,
+		ElasticAttemptsSuppressed,
+		ShadowPlanDivergence,
+		IdleGPUsPerNode,
+		IdleGPUsTotal,
+		DynamicPlansExecuted,
+		PlanToBoundSeconds,
+		IdleGPUsUnabsorbed,
+		ElasticDegradedMode,
 		pendingPods,
 		PodSchedulingDuration,
 		PodSchedulingSLIDuration,
@@ -31,9 +31,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -41,6 +43,7 @@ import (
 	extenderv1 "k8s.io/kube-scheduler/extender/v1"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 	"k8s.io/kubernetes/pkg/apis/core/validation"
+	"k8s.io/kubernetes/pkg/features"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/parallelize"
 	internalqueue "k8s.io/kubernetes/pkg/scheduler/internal/queue"
@@ -68,6 +71,9 @@ const (
 )
 
 func (sched *Scheduler) checkMPIJob(podName string) (string, bool) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return "", false
+	}
 	podNameSlice := strings.Split(podName, "-")
 
 	if len(podNameSlice) < 2 {
@@ -115,15 +121,98 @@ func (sched *Scheduler) getMPIJobRequestGPUcount(ctx context.Context, MPIJobName
 	return requestGPUcount + 1
 }
 
+// markGangUnscheduled marks every pending pod of the MPIJob as unscheduled and
+// records the whole group's GPU demand so the Dynamic retraction path frees
+// room for the gang, not just for a single member pod.
+func (sched *Scheduler) markGangUnscheduled(ctx context.Context, MPIJobName string, ns string, gangRequestGPUs int64) {
+	cachedPods, err := sched.podLister.Pods(ns).List(labels.Everything())
+	if err != nil {
+		klog.Infof("Pods load error in markGangUnscheduled")
+		return
+	}
+	for _, cachedPod := range cachedPods {
+		podMPIJobName, isMPIJob := sched.checkMPIJob(cachedPod.Name)
+		if !isMPIJob || podMPIJobName != MPIJobName {
+			continue
+		}
+		if cachedPod.Spec.NodeName != "" {
+			continue
+		}
+		// The lister serves shared cache objects; mutate a copy.
+		pod := cachedPod.DeepCopy()
+		sched.schedAnnotationSetter(pod, "unscheduled")
+		sched.lock.Lock()
+		pod.Annotations["gang-request-gpus"] = strconv.FormatInt(gangRequestGPUs, 10)
+		sched.lock.Unlock()
+		_, updateErr := sched.client.CoreV1().Pods(pod.Namespace).Update(ctx, pod, metav1.UpdateOptions{})
+		if updateErr != nil {
+			klog.Infof("%v", updateErr)
+			klog.Infof("Fail to update in markGangUnscheduled")
+		}
+	}
+}
+
+// gangAdmitted checks whether the MPIJob's group already passed gang admission.
+func (sched *Scheduler) gangAdmitted(MPIJobName string) bool {
+	sched.lock.RLock()
+	defer sched.lock.RUnlock()
+	_, check := sched.admittedGangs[MPIJobName]
+	return check
+}
+
+func (sched *Scheduler) admitGang(MPIJobName string) {
+	sched.lock.Lock()
+	defer sched.lock.Unlock()
+	if sched.admittedGangs == nil {
+		sched.admittedGangs = make(map[string]time.Time)
+	}
+	sched.admittedGangs[MPIJobName] = time.Now()
+}
+
+// forgetGangIfGone drops the MPIJob's gang-admission entry once none of its
+// pods remain, so a job recreated under the same name passes gang admission
+// again and the map does not grow with every job ever admitted. Called from
+// the pod delete handlers with the pod being removed.
+func (sched *Scheduler) forgetGangIfGone(pod *v1.Pod) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return
+	}
+	if pod.Namespace != util.ElasticJobNamespace() {
+		return
+	}
+	MPIJobName, isMPIJob := sched.checkMPIJob(pod.Name)
+	if !isMPIJob || !sched.gangAdmitted(MPIJobName) {
+		return
+	}
+	if sched.podLister != nil {
+		cachedPods, err := sched.podLister.Pods(pod.Namespace).List(labels.Everything())
+		if err != nil {
+			return
+		}
+		for _, cachedPod := range cachedPods {
+			// The deleted pod itself may still linger in the informer cache.
+			if cachedPod.Name == pod.Name {
+				continue
+			}
+			if name, check := sched.checkMPIJob(cachedPod.Name); check && name == MPIJobName {
+				return
+			}
+		}
+	}
+	sched.lock.Lock()
+	delete(sched.admittedGangs, MPIJobName)
+	sched.lock.Unlock()
+}
+
 func (sched *Scheduler) schedAnnotationSetter(pod *v1.Pod, schedStatus string) string {
 	if pod.Annotations == nil {
 		pod.Annotations = make(map[string]string)
 	}
 	sched.lock.Lock()
-	pod.Annotations["scheduling-state"] = schedStatus
+	pod.Annotations[util.SchedulingStateAnnotation] = schedStatus
 	sched.lock.Unlock()
 
-	if schedState, check := pod.Annotations["scheduling-state"]; check {
+	if schedState, check := pod.Annotations[util.SchedulingStateAnnotation]; check {
 		return schedState
 	} else {
 		return "Fail to set scheduling state"
@@ -131,6 +220,9 @@ func (sched *Scheduler) schedAnnotationSetter(pod *v1.Pod, schedStatus string) s
 }
 
 func (sched *Scheduler) updateAnnotations(ctx context.Context, ns string, name string, status string, schedStatus string) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return
+	}
 	pod, getErr := sched.client.CoreV1().Pods(ns).Get(ctx, name, metav1.GetOptions{})
 	if getErr != nil {
 		klog.Infof("%v", getErr)
@@ -154,7 +246,7 @@ func (sched *Scheduler) updateAnnotations(ctx context.Context, ns string, name s
 // check unscheduled state in activeQ, unschedulablePods, BackoffQ
 func (sched *Scheduler) checkUnscheduled(pods []*v1.Pod) bool {
 	for _, pod := range pods {
-		if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "unscheduled" {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "unscheduled" {
 			return true
 		}
 	}
@@ -168,21 +260,21 @@ func (sched *Scheduler) findEarliestCreationTime() metav1.Time {
 	backoffQ := sched.SchedulingQueue.GetPodsInBackoffQueue()
 
 	for _, pod := range activeQ {
-		if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "unscheduled" {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "unscheduled" {
 			if earliest.IsZero() || pod.CreationTimestamp.Before(&earliest) {
 				earliest = pod.CreationTimestamp
 			}
 		}
 	}
 	for _, pod := range unsQ {
-		if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "unscheduled" {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "unscheduled" {
 			if earliest.IsZero() || pod.CreationTimestamp.Before(&earliest) {
 				earliest = pod.CreationTimestamp
 			}
 		}
 	}
 	for _, pod := range backoffQ {
-		if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "unscheduled" {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "unscheduled" {
 			if earliest.IsZero() || pod.CreationTimestamp.Before(&earliest) {
 				earliest = pod.CreationTimestamp
 			}
@@ -192,18 +284,21 @@ func (sched *Scheduler) findEarliestCreationTime() metav1.Time {
 }
 
 func (sched *Scheduler) backfilledTOscheduled(ctx context.Context, nowPod *v1.Pod, isUnsched bool) {
+	if !utilfeature.DefaultFeatureGate.Enabled(features.DynamicGPUPreemption) {
+		return
+	}
 	pods, err := sched.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
 	if err != nil {
 		klog.Infof("Pods load error in backfilledTOscheduled")
 		return
 	}
 	for _, pod := range pods.Items {
-		if schedStateOfPod, check := pod.Annotations["scheduling-state"]; check && schedStateOfPod == "backfilled" {
+		if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; check && schedStateOfPod == "backfilled" {
 			if isUnsched {
 				earlist := sched.findEarliestCreationTime()
 				if pod.CreationTimestamp.Before(&earlist) && nowPod.CreationTimestamp.Before(&(pod.CreationTimestamp)) {
 					sched.lock.Lock()
-					pod.Annotations["scheduling-state"] = "scheduled"
+					pod.Annotations[util.SchedulingStateAnnotation] = "scheduled"
 					sched.lock.Unlock()
 					klog.Infof("backfilled -> scheduled || %v", pod.Name)
 
@@ -215,7 +310,7 @@ func (sched *Scheduler) backfilledTOscheduled(ctx context.Context, nowPod *v1.Po
 				}
 			} else {
 				sched.lock.Lock()
-				pod.Annotations["scheduling-state"] = "scheduled"
+				pod.Annotations[util.SchedulingStateAnnotation] = "scheduled"
 				sched.lock.Unlock()
 
 				klog.Infof("backfilled -> scheduled || %v", pod.Name)
@@ -245,7 +340,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 
 	// check MPIJob for gang-scheduling
 	MPIJobName, isMPIJob := sched.checkMPIJob(podInfo.Pod.Name)
-	if isMPIJob {
+	if isMPIJob && !sched.gangAdmitted(MPIJobName) {
 		klog.Infof("MPIJob Name : %v", MPIJobName)
 		isFirstAllocate := true
 
@@ -261,7 +356,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 				continue
 			}
 			for _, pod := range pods.Items {
-				if pod.Namespace != "my-ns" {
+				if pod.Namespace != util.ElasticJobNamespace() {
 					continue
 				}
 				runningPodIdx, isRunningPodMPIJob := sched.checkMPIJob(pod.Name)
@@ -278,7 +373,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 		if isFirstAllocate {
 			capacityGPUcount := 0
 			allocatedGPUcount := 0
-			requestGPUcount := sched.getMPIJobRequestGPUcount(ctx, MPIJobName, "my-ns")
+			requestGPUcount := sched.getMPIJobRequestGPUcount(ctx, MPIJobName, util.ElasticJobNamespace())
 			klog.Infof("Request GPU num : %v", requestGPUcount)
 
 			for _, node := range nodes.Items {
@@ -291,7 +386,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 					continue
 				}
 				for _, pod := range pods.Items {
-					if pod.Namespace != "my-ns" {
+					if pod.Namespace != util.ElasticJobNamespace() {
 						continue
 					}
 					for _, container := range pod.Spec.Containers {
@@ -318,8 +413,12 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 				status := framework.NewStatus(framework.UnschedulableAndUnresolvable).WithError(err)
 				sched.FailureHandler(schedulingCycleCtx, fwk, podInfo, status, clearNominatedNode, start)
 				sched.updateAnnotations(ctx, podInfo.Pod.Namespace, podInfo.Pod.Name, "Fail sched | GangScheduling", "unscheduled")
+				// The whole gang waits together; partial placements only waste GPUs.
+				sched.markGangUnscheduled(ctx, MPIJobName, util.ElasticJobNamespace(), requestGPUcount)
 				return
 			}
+			// The whole group fits, so the launcher and all workers are admitted together.
+			sched.admitGang(MPIJobName)
 		}
 	}
 
@@ -400,7 +499,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 				klog.Infof("%v", updateErr)
 				klog.Infof("{success sched} Fail to update")
 			}
-		} else if schedStateOfPod, check := pod.Annotations["scheduling-state"]; !check {
+		} else if schedStateOfPod, check := pod.Annotations[util.SchedulingStateAnnotation]; !check {
 			if sched.checkUnscheduled(sched.SchedulingQueue.GetPodsInActiveQueue()) || sched.checkUnscheduled(sched.SchedulingQueue.GetPodsInUnschedulablePods()) || sched.checkUnscheduled(sched.SchedulingQueue.GetPodsInBackoffQueue()) {
 				setAnno = sched.schedAnnotationSetter(pod, "backfilled")
 			} else {
@@ -32,6 +32,7 @@ import (
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -110,6 +111,14 @@ type Scheduler struct {
 
 	// for lock
 	lock sync.RWMutex
+
+	// admittedGangs records MPIJobs whose whole group (launcher + workers)
+	// fit the cluster at gang-admission time.
+	admittedGangs map[string]time.Time
+
+	// podLister serves the gang-scheduling path from the informer cache
+	// instead of per-failure API lists.
+	podLister corelisters.PodLister
 }
 
 func (sched *Scheduler) applyDefaultHandlers() {
@@ -359,6 +368,8 @@ func New(ctx context.Context,
 		SchedulingQueue:          podQueue,
 		Profiles:                 profiles,
 		logger:                   logger,
+		admittedGangs:            make(map[string]time.Time),
+		podLister:                podLister,
 	}
 	sched.NextPod = podQueue.Pop
 	sched.applyDefaultHandlers()
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// The annotations the scheduler consumes from pods and elastic jobs. Job
+// controllers should reference these variables instead of repeating the
+// literals. The keys are overridable via plugin args (see AnnotationKeys);
+// SetAnnotationKeys rewrites them once at plugin construction, before any
+// scheduling cycle reads them.
+var (
+	// ModelNameAnnotation names the throughput-profile row of the workload.
+	ModelNameAnnotation = "model-name"
+	// SchedulingStateAnnotation tracks how the scheduler placed the pod.
+	SchedulingStateAnnotation = "scheduling-state"
+	// ScaleOutAnnotation records how many workers the scheduler added on top
+	// of the job's baseline.
+	ScaleOutAnnotation = "scale-out"
+)
+
+// RetractableAnnotation, set to "false", keeps a backfilled pod out of
+// retraction victim selection, for jobs that cannot checkpoint.
+const RetractableAnnotation = "scheduler.alpha/retractable"
+
+// AnnotationKeys overrides the keys of the scheduler-consumed annotations,
+// so job operators can align them with site naming conventions. Empty
+// fields keep the defaults above.
+type AnnotationKeys struct {
+	ModelName       string `json:"modelName,omitempty"`
+	SchedulingState string `json:"schedulingState,omitempty"`
+	ScaleOut        string `json:"scaleOut,omitempty"`
+	RetractCheck    string `json:"retractCheck,omitempty"`
+}
+
+// SetAnnotationKeys installs the configured key overrides. It must run
+// before scheduling starts; the keys are read without locking afterwards.
+func SetAnnotationKeys(keys AnnotationKeys) {
+	if keys.ModelName != "" {
+		ModelNameAnnotation = keys.ModelName
+	}
+	if keys.SchedulingState != "" {
+		SchedulingStateAnnotation = keys.SchedulingState
+	}
+	if keys.ScaleOut != "" {
+		ScaleOutAnnotation = keys.ScaleOut
+	}
+	if keys.RetractCheck != "" {
+		RetractCheckAnnotation = keys.RetractCheck
+	}
+}
+
+// PodOptedOutOfRetraction reports whether the pod declared itself
+// non-retractable via RetractableAnnotation.
+func PodOptedOutOfRetraction(pod *v1.Pod) bool {
+	val, check := pod.Annotations[RetractableAnnotation]
+	if !check {
+		return false
+	}
+	retractable, err := strconv.ParseBool(val)
+	return err == nil && !retractable
+}
+
+// The values SchedulingStateAnnotation may hold. The empty string means the
+// retraction path cleared the state.
+const (
+	SchedulingStateUnscheduled = "unscheduled"
+	SchedulingStateBackfilled  = "backfilled"
+	SchedulingStateScheduled   = "scheduled"
+)
+
+// AnnotationViolation describes one malformed scheduler annotation on a pod.
+type AnnotationViolation struct {
+	// Key is the offending annotation.
+	Key string
+	// Value is what the annotation held.
+	Value string
+	// Reason says in one clause why the value is rejected.
+	Reason string
+}
+
+func (violation AnnotationViolation) String() string {
+	return fmt.Sprintf("annotation %v=%q %v", violation.Key, violation.Value, violation.Reason)
+}
+
+// ValidateSchedulerAnnotations checks the scheduler-consumed annotations on
+// <pod> for well-formedness. Absent annotations are fine; only present but
+// malformed values are reported, so a pod cannot reach the elastic machinery
+// with data that would misbehave there.
+func ValidateSchedulerAnnotations(pod *v1.Pod) []AnnotationViolation {
+	var violations []AnnotationViolation
+	if modelName, check := pod.Annotations[ModelNameAnnotation]; check {
+		if strings.TrimSpace(modelName) == "" {
+			violations = append(violations, AnnotationViolation{ModelNameAnnotation, modelName, "must not be empty"})
+		}
+	}
+	if schedState, check := pod.Annotations[SchedulingStateAnnotation]; check {
+		switch schedState {
+		case "", SchedulingStateUnscheduled, SchedulingStateBackfilled, SchedulingStateScheduled:
+		default:
+			violations = append(violations, AnnotationViolation{SchedulingStateAnnotation, schedState, "is not a known scheduling state"})
+		}
+	}
+	if timestampStr, check := pod.Annotations[RetractCheckAnnotation]; check {
+		timestamp, err := time.Parse(time.RFC3339, timestampStr)
+		if err != nil {
+			violations = append(violations, AnnotationViolation{RetractCheckAnnotation, timestampStr, "is not an RFC3339 timestamp"})
+		} else if timestamp.After(time.Now()) {
+			violations = append(violations, AnnotationViolation{RetractCheckAnnotation, timestampStr, "is in the future"})
+		}
+	}
+	if scaleOutStr, check := pod.Annotations[ScaleOutAnnotation]; check {
+		scaleOut, err := strconv.Atoi(scaleOutStr)
+		if err != nil || scaleOut < 0 {
+			violations = append(violations, AnnotationViolation{ScaleOutAnnotation, scaleOutStr, "must be a non-negative integer"})
+		}
+	}
+	if retractableStr, check := pod.Annotations[RetractableAnnotation]; check {
+		if _, err := strconv.ParseBool(retractableStr); err != nil {
+			violations = append(violations, AnnotationViolation{RetractableAnnotation, retractableStr, "must be a boolean"})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWithAnnotations(annotations map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p", Annotations: annotations},
+	}
+}
+
+func TestValidateSchedulerAnnotations(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantKeys    []string
+	}{
+		{
+			name:        "no annotations are fine",
+			annotations: nil,
+			wantKeys:    nil,
+		},
+		{
+			name: "well-formed annotations pass",
+			annotations: map[string]string{
+				ModelNameAnnotation:       "bert",
+				SchedulingStateAnnotation: SchedulingStateBackfilled,
+				RetractCheckAnnotation:    past,
+				ScaleOutAnnotation:        "2",
+				RetractableAnnotation:     "false",
+			},
+			wantKeys: nil,
+		},
+		{
+			name:        "cleared scheduling state passes",
+			annotations: map[string]string{SchedulingStateAnnotation: ""},
+			wantKeys:    nil,
+		},
+		{
+			name:        "blank model name is rejected",
+			annotations: map[string]string{ModelNameAnnotation: "  "},
+			wantKeys:    []string{ModelNameAnnotation},
+		},
+		{
+			name:        "unknown scheduling state is rejected",
+			annotations: map[string]string{SchedulingStateAnnotation: "pending"},
+			wantKeys:    []string{SchedulingStateAnnotation},
+		},
+		{
+			name:        "non-RFC3339 retract-check is rejected",
+			annotations: map[string]string{RetractCheckAnnotation: "yesterday"},
+			wantKeys:    []string{RetractCheckAnnotation},
+		},
+		{
+			name:        "future retract-check is rejected",
+			annotations: map[string]string{RetractCheckAnnotation: future},
+			wantKeys:    []string{RetractCheckAnnotation},
+		},
+		{
+			name:        "non-integer scale-out is rejected",
+			annotations: map[string]string{ScaleOutAnnotation: "two"},
+			wantKeys:    []string{ScaleOutAnnotation},
+		},
+		{
+			name:        "negative scale-out is rejected",
+			annotations: map[string]string{ScaleOutAnnotation: "-1"},
+			wantKeys:    []string{ScaleOutAnnotation},
+		},
+		{
+			name:        "non-boolean retractable is rejected",
+			annotations: map[string]string{RetractableAnnotation: "maybe"},
+			wantKeys:    []string{RetractableAnnotation},
+		},
+		{
+			name: "every malformed annotation is reported",
+			annotations: map[string]string{
+				ModelNameAnnotation:       "",
+				SchedulingStateAnnotation: "pending",
+			},
+			wantKeys: []string{ModelNameAnnotation, SchedulingStateAnnotation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := ValidateSchedulerAnnotations(podWithAnnotations(tt.annotations))
+			var gotKeys []string
+			for _, violation := range violations {
+				gotKeys = append(gotKeys, violation.Key)
+			}
+			if len(gotKeys) != len(tt.wantKeys) {
+				t.Fatalf("ValidateSchedulerAnnotations() violations = %v, want keys %v", violations, tt.wantKeys)
+			}
+			for i, key := range tt.wantKeys {
+				if gotKeys[i] != key {
+					t.Errorf("ValidateSchedulerAnnotations() violation %d key = %v, want %v", i, gotKeys[i], key)
+				}
+			}
+		})
+	}
+}
+
+func TestPodOptedOutOfRetraction(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{
+			name:        "absent annotation keeps the pod retractable",
+			annotations: nil,
+			want:        false,
+		},
+		{
+			name:        "retractable false opts the pod out",
+			annotations: map[string]string{RetractableAnnotation: "false"},
+			want:        true,
+		},
+		{
+			name:        "retractable true keeps the pod retractable",
+			annotations: map[string]string{RetractableAnnotation: "true"},
+			want:        false,
+		},
+		{
+			name:        "malformed value keeps the pod retractable",
+			annotations: map[string]string{RetractableAnnotation: "maybe"},
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PodOptedOutOfRetraction(podWithAnnotations(tt.annotations)); got != tt.want {
+				t.Errorf("PodOptedOutOfRetraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// MPIJobBoostConfig tunes the MPIJob preference in the PrioritySort queue
+// sort so non-MPI clusters can keep vanilla priority sorting.
+type MPIJobBoostConfig struct {
+	// Disabled turns the boost off entirely.
+	Disabled bool `json:"disabled,omitempty"`
+	// BoostWeight, when positive, is added to the boosted pod's priority and
+	// the usual comparison proceeds. When zero the boosted pod wins the
+	// comparison outright, which is the legacy behavior.
+	BoostWeight int64 `json:"boostWeight,omitempty"`
+	// LabelSelector restricts the boost to pods carrying all of these
+	// labels; empty applies the boost to every MPIJob pod.
+	LabelSelector map[string]string `json:"labelSelector,omitempty"`
+}
+
+// AppliesTo reports whether the boost is enabled for <pod>.
+func (boost MPIJobBoostConfig) AppliesTo(pod *v1.Pod) bool {
+	if boost.Disabled {
+		return false
+	}
+	if len(boost.LabelSelector) == 0 {
+		return true
+	}
+	return labels.SelectorFromSet(boost.LabelSelector).Matches(labels.Set(pod.Labels))
+}
+
+// The queue-side elastic scheduling configuration lives here, below both the
+// queue sort and the preemption packages, so the queue sort never has to
+// import the preemption machinery. It is installed once at plugin
+// initialization, before the scheduling queue runs.
+var (
+	elasticJobNamespace = "my-ns"
+	mpiJobBoost         MPIJobBoostConfig
+)
+
+// SetElasticQueueConfig installs the queue-side elastic scheduling config.
+func SetElasticQueueConfig(namespace string, boost MPIJobBoostConfig) {
+	elasticJobNamespace = namespace
+	mpiJobBoost = boost
+}
+
+// ElasticJobNamespace returns the namespace holding elastic jobs.
+func ElasticJobNamespace() string {
+	return elasticJobNamespace
+}
+
+// MPIJobBoost returns the queue-sort boost configuration.
+func MPIJobBoost() MPIJobBoostConfig {
+	return mpiJobBoost
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Fair queueing across tenants: each tenant (a namespace, or whatever the
+// configured label groups by) has a share weight, and the queue sort offers
+// freed GPU capacity to the tenant furthest under its share instead of
+// strictly by pod priority. Like the boost config above, it lives here
+// below both the queue sort and the preemption packages; the config is
+// installed at plugin initialization and usage is fed by the preemption
+// package's informer handlers.
+
+var (
+	fairShareLock     sync.RWMutex
+	fairShares        map[string]int64
+	fairShareLabel    string
+	fairGPUResources  []string
+	tenantGPUUsageMap = map[string]int64{}
+)
+
+// SetFairShareConfig installs the fair-queueing configuration.
+func SetFairShareConfig(shares map[string]int64, label string, gpuResourceNames []string) {
+	fairShareLock.Lock()
+	defer fairShareLock.Unlock()
+	fairShares = shares
+	fairShareLabel = label
+	fairGPUResources = gpuResourceNames
+}
+
+// TenantOfPod names the pod's tenant: the configured label's value when
+// present, the namespace otherwise.
+func TenantOfPod(pod *v1.Pod) string {
+	fairShareLock.RLock()
+	label := fairShareLabel
+	fairShareLock.RUnlock()
+	if label != "" {
+		if tenant, check := pod.Labels[label]; check {
+			return tenant
+		}
+	}
+	return pod.Namespace
+}
+
+// AdjustTenantGPUUsage moves accelerator usage onto or off a tenant as pod
+// events arrive.
+func AdjustTenantGPUUsage(tenant string, delta int64) {
+	fairShareLock.Lock()
+	defer fairShareLock.Unlock()
+	tenantGPUUsageMap[tenant] += delta
+	if tenantGPUUsageMap[tenant] <= 0 {
+		delete(tenantGPUUsageMap, tenant)
+	}
+}
+
+// fairShareGPUs sums the pod's requests over the configured accelerator
+// resources, counting limits when a container sets no request.
+func fairShareGPUs(pod *v1.Pod) int64 {
+	var total int64
+	for _, container := range pod.Spec.Containers {
+		for _, name := range fairGPUResources {
+			if request, ok := container.Resources.Requests[v1.ResourceName(name)]; ok {
+				total += request.Value()
+				continue
+			}
+			if limit, ok := container.Resources.Limits[v1.ResourceName(name)]; ok {
+				total += limit.Value()
+			}
+		}
+	}
+	return total
+}
+
+// FairShareRank returns the pod's tenant usage normalized by the tenant's
+// configured weight. Lower ranks are more underserved and dequeue first.
+// ok is false when fair queueing is not configured or the pod asks for no
+// accelerators, leaving the caller on priority ordering.
+func FairShareRank(pod *v1.Pod) (rank float64, ok bool) {
+	fairShareLock.RLock()
+	defer fairShareLock.RUnlock()
+	if len(fairShares) == 0 {
+		return 0, false
+	}
+	if fairShareGPUs(pod) == 0 {
+		return 0, false
+	}
+	tenant := pod.Namespace
+	if fairShareLabel != "" {
+		if labeled, check := pod.Labels[fairShareLabel]; check {
+			tenant = labeled
+		}
+	}
+	weight := fairShares[tenant]
+	if weight <= 0 {
+		weight = 1
+	}
+	return float64(tenantGPUUsageMap[tenant]) / float64(weight), true
+}
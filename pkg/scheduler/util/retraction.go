@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// RetractCheckAnnotation carries the queue position a retracted pod inherits:
+// the creation timestamp of the pod it was retracted for, in RFC3339. The
+// key is overridable via AnnotationKeys.
+var RetractCheckAnnotation = "retract-check-var"
+
+// RetractionTimestamp returns the timestamp used for retraction-aware queue
+// ordering: the retract-check-var annotation when present and valid,
+// <fallback> otherwise. The second return value reports whether the
+// annotation was used. Malformed values and timestamps in the future are
+// rejected so a corrupt annotation can neither jump the queue (zero time)
+// nor pin the pod to its back.
+func RetractionTimestamp(pod *v1.Pod, fallback time.Time) (time.Time, bool) {
+	timestampStr, check := pod.Annotations[RetractCheckAnnotation]
+	if !check {
+		return fallback, false
+	}
+	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+	if err != nil {
+		klog.Infof("Ignoring malformed %v on %v: %v", RetractCheckAnnotation, pod.Name, err)
+		return fallback, false
+	}
+	if timestamp.After(time.Now()) {
+		klog.Infof("Ignoring future %v on %v", RetractCheckAnnotation, pod.Name)
+		return fallback, false
+	}
+	return timestamp, true
+}
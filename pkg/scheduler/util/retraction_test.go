@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWithRetractCheck(val string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p"},
+	}
+	if val != "" {
+		pod.Annotations = map[string]string{RetractCheckAnnotation: val}
+	}
+	return pod
+}
+
+func TestRetractionTimestamp(t *testing.T) {
+	fallback := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := fallback.Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		annotation   string
+		wantTime     time.Time
+		wantFromAnno bool
+	}{
+		{
+			name:         "no annotation falls back",
+			annotation:   "",
+			wantTime:     fallback,
+			wantFromAnno: false,
+		},
+		{
+			name:         "valid annotation wins",
+			annotation:   valid.Format(time.RFC3339),
+			wantTime:     valid,
+			wantFromAnno: true,
+		},
+		{
+			name:         "malformed annotation falls back",
+			annotation:   "not-a-timestamp",
+			wantTime:     fallback,
+			wantFromAnno: false,
+		},
+		{
+			name:         "future annotation falls back",
+			annotation:   time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+			wantTime:     fallback,
+			wantFromAnno: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fromAnno := RetractionTimestamp(podWithRetractCheck(tt.annotation), fallback)
+			if !got.Equal(tt.wantTime) {
+				t.Errorf("RetractionTimestamp() time = %v, want %v", got, tt.wantTime)
+			}
+			if fromAnno != tt.wantFromAnno {
+				t.Errorf("RetractionTimestamp() fromAnnotation = %v, want %v", fromAnno, tt.wantFromAnno)
+			}
+		})
+	}
+}
+
+// TestRetractionTimestampOrdering checks the queue ordering invariants: a
+// retracted pod sorts by its inherited timestamp against both retracted and
+// never-retracted pods, and a malformed annotation cannot jump the queue.
+func TestRetractionTimestampOrdering(t *testing.T) {
+	older := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	retractedOld, _ := RetractionTimestamp(podWithRetractCheck(older.Format(time.RFC3339)), newer)
+	plainNew, _ := RetractionTimestamp(podWithRetractCheck(""), newer)
+	if !retractedOld.Before(plainNew) && !plainNew.After(retractedOld) {
+		t.Errorf("retracted pod with older inherited timestamp should order before a newer pod")
+	}
+
+	malformed, _ := RetractionTimestamp(podWithRetractCheck("garbage"), newer)
+	if malformed.Before(retractedOld) {
+		t.Errorf("malformed annotation must not order before a validly retracted pod")
+	}
+}
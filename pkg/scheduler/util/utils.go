@@ -29,15 +29,24 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	"k8s.io/klog/v2"
 	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+	apipod "k8s.io/kubernetes/pkg/api/v1/pod"
 	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+	"k8s.io/kubernetes/pkg/features"
 )
 
+// PodReasonRetractedByScheduler is the DisruptionTarget condition reason set
+// on a backfilled pod that is retracted, so controllers and users can tell a
+// retraction from a preemption or any other deletion.
+const PodReasonRetractedByScheduler = "RetractedByScheduler"
+
 // GetPodFullName returns a name that uniquely identifies a pod.
 func GetPodFullName(pod *v1.Pod) string {
 	// Use underscore as the delimiter because it is not allowed in pod name
@@ -138,8 +147,47 @@ func DeletePod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) error
 	return cs.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
 }
 
-// RetractPod retracts the victim <pod> from API server
-func RetractPod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) error {
+// retractSignalTimeout bounds how long a backfilled pod may spend
+// checkpointing after the pre-retraction signal before it is deleted anyway.
+const retractSignalTimeout = 30 * time.Second
+
+// signalRetraction notifies the victim pod that it is about to be retracted
+// (via the pre-retract-signal annotation) and waits up to a timeout for the
+// pod to acknowledge with pre-retract-ack, so training state can be
+// checkpointed before the deletion.
+func signalRetraction(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) {
+	livePod, getErr := cs.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if getErr != nil {
+		klog.Infof("Fail to get pod in signalRetraction: %v", getErr)
+		return
+	}
+	if _, check := livePod.Annotations["pre-retract-signal"]; !check {
+		if livePod.Annotations == nil {
+			livePod.Annotations = make(map[string]string)
+		}
+		livePod.Annotations["pre-retract-signal"] = time.Now().Format(time.RFC3339)
+		if _, updateErr := cs.CoreV1().Pods(livePod.Namespace).Update(ctx, livePod, metav1.UpdateOptions{}); updateErr != nil {
+			klog.Infof("Fail to set pre-retract-signal: %v", updateErr)
+			return
+		}
+	}
+	pollErr := wait.PollUntilContextTimeout(ctx, time.Second, retractSignalTimeout, true, func(ctx context.Context) (bool, error) {
+		p, err := cs.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		_, check := p.Annotations["pre-retract-ack"]
+		return check, nil
+	})
+	if pollErr != nil {
+		klog.Infof("No retract ack from %v, retracting anyway", pod.Name)
+	}
+}
+
+// RetractPod retracts the victim <pod> from API server. The given
+// <auditAnnotations> are stamped onto the re-created pod so audit pipelines
+// can correlate the retraction with the scheduling decision.
+func RetractPod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod, auditAnnotations map[string]string) error {
 	victimPod := &v1.Pod{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       pod.TypeMeta.Kind,
@@ -188,14 +236,41 @@ func RetractPod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) error
 		},
 	}
 
-	if _, check := victimPod.ObjectMeta.Annotations["retract-check-var"]; !check {
-		victimPod.ObjectMeta.Annotations["retract-check-var"] = pod.ObjectMeta.CreationTimestamp.Format(time.RFC3339)
+	if victimPod.ObjectMeta.Annotations == nil {
+		victimPod.ObjectMeta.Annotations = make(map[string]string)
+	}
+	for key, val := range auditAnnotations {
+		victimPod.ObjectMeta.Annotations[key] = val
 	}
 
-	if schedStateOfPod, check := victimPod.ObjectMeta.Annotations["scheduling-state"]; check && schedStateOfPod == "backfilled" {
-		victimPod.ObjectMeta.Annotations["scheduling-state"] = ""
+	if _, check := victimPod.ObjectMeta.Annotations[RetractCheckAnnotation]; !check {
+		victimPod.ObjectMeta.Annotations[RetractCheckAnnotation] = pod.ObjectMeta.CreationTimestamp.Format(time.RFC3339)
 	}
 
+	if schedStateOfPod, check := victimPod.ObjectMeta.Annotations[SchedulingStateAnnotation]; check && schedStateOfPod == "backfilled" {
+		victimPod.ObjectMeta.Annotations[SchedulingStateAnnotation] = ""
+	}
+
+	// Mark the victim like prepareCandidate marks preemption victims, with a
+	// retraction-specific reason.
+	if utilfeature.DefaultFeatureGate.Enabled(features.PodDisruptionConditions) {
+		condition := &v1.PodCondition{
+			Type:    v1.DisruptionTarget,
+			Status:  v1.ConditionTrue,
+			Reason:  PodReasonRetractedByScheduler,
+			Message: fmt.Sprintf("%s: retracting backfilled pod to return its GPUs", pod.Spec.SchedulerName),
+		}
+		newStatus := pod.Status.DeepCopy()
+		if apipod.UpdatePodCondition(newStatus, condition) {
+			if err := PatchPodStatus(ctx, cs, pod, newStatus); err != nil {
+				klog.Infof("Could not add DisruptionTarget condition due to retraction: %v", err)
+			}
+		}
+	}
+
+	// Give the victim a chance to checkpoint before it disappears.
+	signalRetraction(ctx, cs, pod)
+
 	deleteErr := cs.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
 	if deleteErr != nil {
 		return fmt.Errorf("failed to delete the pod: %v", deleteErr)
@@ -216,6 +291,14 @@ func RetractPod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) error
 	return nil
 }
 
+// ForceDeletePod removes the pod with zero grace period. It is reserved for
+// victims on NotReady nodes in fencing-capable clusters, where a graceful
+// retraction would hang on an unreachable kubelet.
+func ForceDeletePod(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) error {
+	gracePeriod := int64(0)
+	return cs.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+}
+
 // ClearNominatedNodeName internally submit a patch request to API server
 // to set each pods[*].Status.NominatedNodeName> to "".
 func ClearNominatedNodeName(ctx context.Context, cs kubernetes.Interface, pods ...*v1.Pod) utilerrors.Aggregate {
@@ -19,6 +19,7 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -41,6 +42,12 @@ type DefaultPreemptionArgs struct {
 	// that play a role in the number of candidates shortlisted. Must be at least
 	// 0 nodes. Defaults to 100 nodes if unspecified.
 	MinCandidateNodesAbsolute *int32 `json:"minCandidateNodesAbsolute,omitempty"`
+
+	// DynamicPreemption carries the configuration of the Dynamic GPU
+	// preemption path as a raw object; the scheduler decodes it into its
+	// Dynamic args schema. Nil keeps the builtin defaults.
+	// +optional
+	DynamicPreemption *runtime.RawExtension `json:"dynamicPreemption,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -40,6 +40,11 @@ func (in *DefaultPreemptionArgs) DeepCopyInto(out *DefaultPreemptionArgs) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.DynamicPreemption != nil {
+		in, out := &in.DynamicPreemption, &out.DynamicPreemption
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 